@@ -356,10 +356,7 @@ export default function Game() {
     }
     
     if (socketRef.current && socketRef.current.readyState === WebSocket.OPEN) {
-      socketRef.current.send(JSON.stringify({
-        type: 'game_over',
-        payload: { result: winner === 'white' ? '1-0' : '0-1', reason: 'resign', winner }
-      }));
+      socketRef.current.send(JSON.stringify({ type: 'resign' }));
     }
   }
 
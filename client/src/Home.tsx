@@ -67,8 +67,7 @@ export default function Home() {
           // Join queue with selected mode
           await api.post('/matchmaking/join', {
               mode: mode.toLowerCase(),
-              time_control: timeControl,
-              rating: 1200
+              time_control: timeControl
           });
           
           // Poll for active match
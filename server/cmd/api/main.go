@@ -1,7 +1,9 @@
 package main
 
 import (
+	"expvar"
 	"log"
+	"net/http"
 
 	"github.com/datmedevil17/chesss/internal/api"
 	"github.com/datmedevil17/chesss/internal/config"
@@ -24,6 +26,19 @@ func main() {
 
 	r := api.InitRouter(cfg)
 
+	// Runtime metrics, including the number of live game rooms, on their
+	// own listener so they are never exposed with the public API.
+	if cfg.DebugAddr != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/debug/vars", expvar.Handler())
+			log.Printf("Debug server listening on %s", cfg.DebugAddr)
+			if err := http.ListenAndServe(cfg.DebugAddr, mux); err != nil {
+				log.Printf("Debug server stopped: %v", err)
+			}
+		}()
+	}
+
 	log.Printf("Server starting on port %s", cfg.Port)
 	if err := r.Run(cfg.Port); err != nil {
 		log.Fatalf("Failed to run server: %v", err)
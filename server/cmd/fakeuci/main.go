@@ -0,0 +1,363 @@
+// Command fakeuci is a tiny UCI engine for exercising the engine package
+// without Stockfish. It scores each legal move by material after one ply,
+// reports one info line per depth and plays the best-scoring move.
+//
+//	go build -o /tmp/fakeuci ./cmd/fakeuci
+//	STOCKFISH_PATH=/tmp/fakeuci go run ./cmd/api
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/chess"
+)
+
+var (
+	depthDelay = flag.Duration("delay", 10*time.Millisecond, "time spent on each depth")
+	maxDepth   = flag.Int("depth", 8, "depth reached when no limit is given")
+	ignoreStop = flag.Bool("ignore-stop", false, "keep searching after stop, to test timeouts")
+)
+
+var pieceValues = [...]int{chess.Pawn: 100, chess.Knight: 300, chess.Bishop: 300, chess.Rook: 500, chess.Queen: 900}
+
+type engine struct {
+	out       *bufio.Writer
+	pos       *chess.Position
+	multiPV   int
+	stop      chan struct{} // closed to end the running search
+	ponderhit chan struct{}
+	finished  chan struct{} // closed when the running search has printed bestmove
+}
+
+func main() {
+	flag.Parse()
+	e := &engine{out: bufio.NewWriter(os.Stdout), pos: chess.StartingPosition(), multiPV: 1}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if !e.handle(strings.Fields(scanner.Text())) {
+			break
+		}
+	}
+	e.wait()
+}
+
+func (e *engine) printf(format string, args ...interface{}) {
+	fmt.Fprintf(e.out, format+"\n", args...)
+	e.out.Flush()
+}
+
+// handle runs one command and reports whether to keep reading.
+func (e *engine) handle(f []string) bool {
+	if len(f) == 0 {
+		return true
+	}
+	switch f[0] {
+	case "uci":
+		e.printf("id name fakeuci")
+		e.printf("id author chesss")
+		e.printf("option name MultiPV type spin default 1 min 1 max 64")
+		e.printf("option name Skill Level type spin default 20 min 0 max 20")
+		e.printf("option name UCI_LimitStrength type check default false")
+		e.printf("option name UCI_Elo type spin default 1320 min 1320 max 3190")
+		e.printf("uciok")
+	case "isready":
+		e.wait()
+		e.printf("readyok")
+	case "setoption":
+		e.setOption(f[1:])
+	case "ucinewgame":
+		e.wait()
+		e.pos = chess.StartingPosition()
+	case "position":
+		e.wait()
+		if err := e.position(f[1:]); err != nil {
+			e.printf("info string %v", err)
+		}
+	case "go":
+		e.wait()
+		e.goSearch(f[1:])
+	case "stop":
+		if e.stop != nil && !*ignoreStop {
+			closeOnce(e.stop)
+		}
+	case "ponderhit":
+		if e.ponderhit != nil {
+			closeOnce(e.ponderhit)
+		}
+	case "quit":
+		if e.stop != nil {
+			closeOnce(e.stop)
+		}
+		return false
+	}
+	return true
+}
+
+func closeOnce(ch chan struct{}) {
+	select {
+	case <-ch:
+	default:
+		close(ch)
+	}
+}
+
+// wait blocks until the running search, if any, has finished.
+func (e *engine) wait() {
+	if e.finished != nil {
+		<-e.finished
+	}
+}
+
+func (e *engine) setOption(f []string) {
+	// setoption name <name...> value <value>
+	var name, value []string
+	dst := &name
+	for _, w := range f {
+		switch w {
+		case "name":
+			dst = &name
+		case "value":
+			dst = &value
+		default:
+			*dst = append(*dst, w)
+		}
+	}
+	if strings.Join(name, " ") == "MultiPV" && len(value) == 1 {
+		if n, err := strconv.Atoi(value[0]); err == nil && n > 0 {
+			e.multiPV = n
+		}
+	}
+}
+
+func (e *engine) position(f []string) error {
+	if len(f) == 0 {
+		return fmt.Errorf("missing position")
+	}
+	var pos *chess.Position
+	rest := f[1:]
+	switch f[0] {
+	case "startpos":
+		pos = chess.StartingPosition()
+	case "fen":
+		n := len(rest)
+		for i, w := range rest {
+			if w == "moves" {
+				n = i
+				break
+			}
+		}
+		p, err := chess.ParseFEN(strings.Join(rest[:n], " "))
+		if err != nil {
+			return err
+		}
+		pos, rest = p, rest[n:]
+	default:
+		return fmt.Errorf("unknown position %q", f[0])
+	}
+	if len(rest) > 0 && rest[0] == "moves" {
+		for _, uci := range rest[1:] {
+			m, err := pos.ParseMove(uci)
+			if err != nil {
+				return err
+			}
+			pos = pos.Play(m)
+		}
+	}
+	e.pos = pos
+	return nil
+}
+
+type scoredMove struct {
+	move  chess.Move
+	reply chess.Move // best answer, for the second pv move
+	cp    int
+	mate  bool
+}
+
+func (e *engine) goSearch(f []string) {
+	depth, infinite, ponder := 0, false, false
+	var deadline time.Duration
+	for i := 0; i < len(f); i++ {
+		arg := func() int {
+			if i+1 < len(f) {
+				i++
+				n, _ := strconv.Atoi(f[i])
+				return n
+			}
+			return 0
+		}
+		switch f[i] {
+		case "depth":
+			depth = arg()
+		case "movetime":
+			deadline = time.Duration(arg()) * time.Millisecond
+		case "wtime", "btime", "winc", "binc", "movestogo", "nodes":
+			// Clock limits are honoured by stopping at maxDepth.
+			arg()
+		case "infinite":
+			infinite = true
+		case "ponder":
+			ponder = true
+		}
+	}
+	if depth == 0 {
+		depth = *maxDepth
+	}
+
+	e.stop = make(chan struct{})
+	e.ponderhit = make(chan struct{})
+	e.finished = make(chan struct{})
+	go e.search(e.pos, e.multiPV, depth, deadline, infinite, ponder, e.stop, e.ponderhit, e.finished)
+}
+
+func (e *engine) search(pos *chess.Position, multiPV, depth int, deadline time.Duration, infinite, ponder bool, stop, ponderhit, finished chan struct{}) {
+	defer close(finished)
+
+	moves := rank(pos)
+	if len(moves) == 0 {
+		e.printf("info depth 0 score %s", terminalScore(pos))
+		<-untilReleased(infinite || ponder, stop, ponderhit)
+		e.printf("bestmove (none)")
+		return
+	}
+	if multiPV > len(moves) {
+		multiPV = len(moves)
+	}
+
+	start := time.Now()
+	var timeout <-chan time.Time
+	if deadline > 0 {
+		timeout = time.After(deadline)
+	}
+	nodes := 0
+	for d := 1; ; d++ {
+		if d > depth {
+			// Limits reached; infinite and ponder searches still wait.
+			<-untilReleased(infinite || ponder, stop, ponderhit)
+			break
+		}
+		select {
+		case <-stop:
+		case <-timeout:
+		case <-time.After(*depthDelay):
+			nodes += len(moves) * d * 100
+			elapsed := time.Since(start)
+			for i, m := range moves[:multiPV] {
+				e.printf("info depth %d seldepth %d multipv %d score %s nodes %d nps %d hashfull %d time %d pv %s",
+					d, d+2, i+1, m.score(), nodes, nps(nodes, elapsed), d*10, elapsed.Milliseconds(), m.pv())
+			}
+			continue
+		}
+		break
+	}
+
+	best := moves[0]
+	if best.reply.From == best.reply.To {
+		e.printf("bestmove %s", best.move)
+		return
+	}
+	e.printf("bestmove %s ponder %s", best.move, best.reply)
+}
+
+// untilReleased returns a channel that is ready once a search that must not
+// end on its own has been stopped, or at once for normal searches.
+func untilReleased(hold bool, stop, ponderhit chan struct{}) <-chan struct{} {
+	if !hold {
+		ch := make(chan struct{})
+		close(ch)
+		return ch
+	}
+	released := make(chan struct{})
+	go func() {
+		select {
+		case <-stop:
+		case <-ponderhit:
+		}
+		close(released)
+	}()
+	return released
+}
+
+func nps(nodes int, elapsed time.Duration) int {
+	if elapsed <= 0 {
+		return 0
+	}
+	return int(float64(nodes) / elapsed.Seconds())
+}
+
+func (m scoredMove) score() string {
+	if m.mate {
+		return "mate 1"
+	}
+	return fmt.Sprintf("cp %d", m.cp)
+}
+
+func (m scoredMove) pv() string {
+	if m.reply.From == m.reply.To {
+		return m.move.String()
+	}
+	return m.move.String() + " " + m.reply.String()
+}
+
+func terminalScore(pos *chess.Position) string {
+	if pos.InCheck() {
+		return "mate 0"
+	}
+	return "cp 0"
+}
+
+// rank orders the legal moves by the material balance after the opponent's
+// best recapture, from the mover's point of view.
+func rank(pos *chess.Position) []scoredMove {
+	var moves []scoredMove
+	for _, m := range pos.LegalMoves() {
+		next := pos.Play(m)
+		sm := scoredMove{move: m}
+		replies := next.LegalMoves()
+		if len(replies) == 0 {
+			sm.mate = next.InCheck()
+			moves = append(moves, sm)
+			continue
+		}
+		worst := 0
+		for i, r := range replies {
+			cp := material(next.Play(r), pos.Turn())
+			if i == 0 || cp < worst {
+				worst, sm.reply = cp, r
+			}
+		}
+		sm.cp = worst
+		moves = append(moves, sm)
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		a, b := moves[i], moves[j]
+		if a.mate != b.mate {
+			return a.mate
+		}
+		return a.cp > b.cp
+	})
+	return moves
+}
+
+func material(pos *chess.Position, c chess.Color) int {
+	total := 0
+	for sq := chess.Square(0); sq < 64; sq++ {
+		p := pos.PieceAt(sq)
+		if p == chess.NoPiece || p.Type() == chess.King {
+			continue
+		}
+		if p.Color() == c {
+			total += pieceValues[p.Type()]
+		} else {
+			total -= pieceValues[p.Type()]
+		}
+	}
+	return total
+}
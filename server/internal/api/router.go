@@ -1,11 +1,21 @@
 package api
 
 import (
+	"context"
+
 	"github.com/datmedevil17/chesss/internal/config"
+	"github.com/datmedevil17/chesss/internal/handlers/ai"
+	"github.com/datmedevil17/chesss/internal/handlers/challenge"
+	eventshandler "github.com/datmedevil17/chesss/internal/handlers/events"
 	"github.com/datmedevil17/chesss/internal/handlers/game"
+	"github.com/datmedevil17/chesss/internal/handlers/leaderboard"
 	"github.com/datmedevil17/chesss/internal/handlers/matchmaking"
 	"github.com/datmedevil17/chesss/internal/handlers/user"
 	"github.com/datmedevil17/chesss/internal/middleware"
+	"github.com/datmedevil17/chesss/internal/services/analysis"
+	"github.com/datmedevil17/chesss/internal/services/engine"
+	"github.com/datmedevil17/chesss/internal/services/events"
+	mmservice "github.com/datmedevil17/chesss/internal/services/matchmaking"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,10 +25,24 @@ func InitRouter(cfg *config.Config) *gin.Engine {
 	// Middleware
 	r.Use(middleware.CORSMiddleware())
 
+	// Per-user notifications, published by services and streamed at /events
+	dispatcher := events.NewDispatcher()
+
 	// Handlers
 	userHandler := user.NewHandler()
-	matchmakingHandler := matchmaking.NewHandler()
-	gameHandler := game.NewHandler(cfg.JWTSecret)
+	matchmakingHandler := matchmaking.NewHandler(dispatcher)
+	// Pairs queued players in the background, not only when someone joins
+	go mmservice.NewService(dispatcher).RunMatcher(context.Background())
+	// Engine processes are shared by every bot
+	enginePool := engine.NewPool(cfg.StockfishPath, cfg.EnginePoolSize)
+
+	analyzer := analysis.NewService(enginePool)
+
+	gameHandler := game.NewHandler(cfg, enginePool, analyzer, dispatcher)
+	aiHandler := ai.NewHandler()
+	eventsHandler := eventshandler.NewHandler(cfg, dispatcher)
+	challengeHandler := challenge.NewHandler(dispatcher)
+	leaderboardHandler := leaderboard.NewHandler(cfg)
 
 	api := r.Group("/api/v1")
 	{
@@ -30,6 +54,9 @@ func InitRouter(cfg *config.Config) *gin.Engine {
 			auth.GET("/me", middleware.AuthMiddleware(cfg.JWTSecret), userHandler.Me)
 		}
 
+		// Event stream (authenticates itself, to allow ?token=)
+		api.GET("/events", eventsHandler.Stream)
+
 		// Matchmaking Routes
 		mm := api.Group("/matchmaking")
 		mm.Use(middleware.AuthMiddleware(cfg.JWTSecret))
@@ -39,11 +66,51 @@ func InitRouter(cfg *config.Config) *gin.Engine {
 			mm.GET("/active", matchmakingHandler.CheckActiveMatch)
 		}
 
+		users := api.Group("/users")
+		{
+			users.GET("/:username", userHandler.Profile)
+			users.GET("/:username/games", gameHandler.ListUserGames)
+		}
+
+		// Top players per mode (a token adds the caller's own rank)
+		api.GET("/leaderboards/:mode", leaderboardHandler.Get)
+
+		// Direct challenges between users
+		challenges := api.Group("/challenges")
+		challenges.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+		{
+			challenges.POST("", challengeHandler.Create)
+			challenges.GET("", challengeHandler.List)
+			challenges.POST("/:id/accept", challengeHandler.Accept)
+			challenges.POST("/:id/decline", challengeHandler.Decline)
+			challenges.POST("/:id/cancel", challengeHandler.Cancel)
+		}
+
 		// Game Routes
 		g := api.Group("/game")
 		{
 			g.GET("/ws/:gameId", gameHandler.WSHandler)
 		}
+
+		// Featured game, switching to the next one as each ends
+		api.GET("/tv/ws", gameHandler.TVHandler)
+
+		// Games against the engine
+		aiGroup := api.Group("/ai")
+		aiGroup.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+		{
+			aiGroup.POST("/games", aiHandler.CreateGame)
+		}
+
+		games := api.Group("/games")
+		{
+			games.POST("/import", middleware.AuthMiddleware(cfg.JWTSecret), gameHandler.ImportPGN)
+			games.GET("/live", gameHandler.LiveGames)
+			games.GET("/:id", gameHandler.GetGame)
+			games.GET("/:id/pgn", gameHandler.ExportPGN)
+			games.POST("/:id/analysis", middleware.AuthMiddleware(cfg.JWTSecret), gameHandler.RequestAnalysis)
+			games.GET("/:id/analysis", gameHandler.GetAnalysis)
+		}
 	}
 
 	return r
@@ -0,0 +1,163 @@
+package chess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseFEN decodes a Forsyth-Edwards Notation string. The move counters
+// may be omitted, in which case they default to "0 1".
+func ParseFEN(fen string) (*Position, error) {
+	fields := strings.Fields(fen)
+	if len(fields) != 4 && len(fields) != 6 {
+		return nil, fmt.Errorf("invalid fen %q: expected 4 or 6 fields", fen)
+	}
+
+	p := &Position{enPassant: NoSquare, fullmoveNumber: 1}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("invalid fen %q: expected 8 ranks", fen)
+	}
+	for i, row := range ranks {
+		rank := 7 - i
+		file := 0
+		for j := 0; j < len(row); j++ {
+			ch := row[j]
+			if ch >= '1' && ch <= '8' {
+				file += int(ch - '0')
+				continue
+			}
+			piece, ok := pieceFromLetter(ch)
+			if !ok || file > 7 {
+				return nil, fmt.Errorf("invalid fen %q: bad rank %q", fen, row)
+			}
+			p.board[NewSquare(file, rank)] = piece
+			file++
+		}
+		if file != 8 {
+			return nil, fmt.Errorf("invalid fen %q: bad rank %q", fen, row)
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		p.turn = White
+	case "b":
+		p.turn = Black
+	default:
+		return nil, fmt.Errorf("invalid fen %q: bad side to move", fen)
+	}
+
+	if fields[2] != "-" {
+		for _, ch := range fields[2] {
+			switch ch {
+			case 'K':
+				p.castling |= WhiteKingSide
+			case 'Q':
+				p.castling |= WhiteQueenSide
+			case 'k':
+				p.castling |= BlackKingSide
+			case 'q':
+				p.castling |= BlackQueenSide
+			default:
+				return nil, fmt.Errorf("invalid fen %q: bad castling rights", fen)
+			}
+		}
+	}
+
+	if fields[3] != "-" {
+		sq, err := ParseSquare(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid fen %q: bad en passant square", fen)
+		}
+		p.enPassant = sq
+	}
+
+	if len(fields) == 6 {
+		var err error
+		if p.halfmoveClock, err = strconv.Atoi(fields[4]); err != nil || p.halfmoveClock < 0 {
+			return nil, fmt.Errorf("invalid fen %q: bad halfmove clock", fen)
+		}
+		if p.fullmoveNumber, err = strconv.Atoi(fields[5]); err != nil || p.fullmoveNumber < 1 {
+			return nil, fmt.Errorf("invalid fen %q: bad fullmove number", fen)
+		}
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid fen %q: %w", fen, err)
+	}
+	return p, nil
+}
+
+// validate rejects positions the move generator cannot reason about.
+func (p *Position) validate() error {
+	for _, c := range []Color{White, Black} {
+		kings := 0
+		for sq := Square(0); sq < 64; sq++ {
+			if p.board[sq] == NewPiece(c, King) {
+				kings++
+			}
+		}
+		if kings != 1 {
+			return fmt.Errorf("%s must have exactly one king", c)
+		}
+	}
+	for file := 0; file < 8; file++ {
+		if p.board[NewSquare(file, 0)].Type() == Pawn || p.board[NewSquare(file, 7)].Type() == Pawn {
+			return fmt.Errorf("pawn on back rank")
+		}
+	}
+	if p.enPassant != NoSquare {
+		// The square was just skipped by an enemy pawn, which now stands
+		// right behind it.
+		rank, behind := 5, -1
+		if p.turn == Black {
+			rank, behind = 2, 1
+		}
+		pawn := offset(p.enPassant, 0, behind)
+		if p.enPassant.Rank() != rank || p.board[p.enPassant] != NoPiece ||
+			p.board[pawn] != NewPiece(p.turn.Other(), Pawn) {
+			return fmt.Errorf("impossible en passant square %s", p.enPassant)
+		}
+	}
+	k := p.kingSquare(p.turn.Other())
+	if p.isAttacked(k, p.turn) {
+		return fmt.Errorf("side not to move is in check")
+	}
+	return nil
+}
+
+// FEN encodes the position as a Forsyth-Edwards Notation string.
+func (p *Position) FEN() string {
+	var sb strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			piece := p.board[NewSquare(file, rank)]
+			if piece == NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			sb.WriteByte(piece.Letter())
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if rank > 0 {
+			sb.WriteByte('/')
+		}
+	}
+
+	turn := "w"
+	if p.turn == Black {
+		turn = "b"
+	}
+	fmt.Fprintf(&sb, " %s %s %s %d %d", turn, p.castling, p.enPassant, p.halfmoveClock, p.fullmoveNumber)
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package chess
+
+import "testing"
+
+func TestFENRoundTrip(t *testing.T) {
+	fens := []string{
+		StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3",
+		"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2",
+	}
+	for _, fen := range fens {
+		p, err := ParseFEN(fen)
+		if err != nil {
+			t.Errorf("ParseFEN(%q): %v", fen, err)
+			continue
+		}
+		if got := p.FEN(); got != fen {
+			t.Errorf("FEN() = %q, want %q", got, fen)
+		}
+	}
+}
+
+func TestParseFENDefaultsCounters(t *testing.T) {
+	p, err := ParseFEN("4k3/8/8/8/8/8/8/4K3 w - -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.HalfmoveClock() != 0 || p.FullmoveNumber() != 1 {
+		t.Errorf("counters = %d %d, want 0 1", p.HalfmoveClock(), p.FullmoveNumber())
+	}
+}
+
+func TestParseFENRejectsInvalid(t *testing.T) {
+	fens := map[string]string{
+		"too few fields":       "4k3/8/8/8/8/8/8/4K3 w",
+		"seven ranks":          "4k3/8/8/8/8/8/4K3 w - - 0 1",
+		"long rank":            "4k3/9/8/8/8/8/8/4K3 w - - 0 1",
+		"bad piece":            "4k3/8/8/8/8/8/8/4X3 w - - 0 1",
+		"bad side":             "4k3/8/8/8/8/8/8/4K3 x - - 0 1",
+		"bad castling":         "4k3/8/8/8/8/8/8/4K3 w X - 0 1",
+		"no white king":        "4k3/8/8/8/8/8/8/8 w - - 0 1",
+		"two black kings":      "4k2k/8/8/8/8/8/8/4K3 w - - 0 1",
+		"pawn on back rank":    "4k2P/8/8/8/8/8/8/4K3 w - - 0 1",
+		"opponent in check":    "4k3/8/8/8/8/8/8/4R1K1 w - - 0 1",
+		"bad fullmove number":  "4k3/8/8/8/8/8/8/4K3 w - - 0 0",
+		"en passant off rank":  "4k3/8/8/8/8/8/3PK3/8 w - e3 0 1",
+		"en passant no pawn":   "4k3/8/8/8/8/8/8/4K3 w - e6 0 1",
+		"en passant own pawn":  "4k3/8/8/4P3/8/8/8/4K3 w - e6 0 1",
+		"en passant occupied":  "4k3/8/4n3/4p3/8/8/8/4K3 w - e6 0 1",
+		"en passant bad field": "4k3/8/8/8/8/8/8/4K3 w - z9 0 1",
+	}
+	for name, fen := range fens {
+		if _, err := ParseFEN(fen); err == nil {
+			t.Errorf("%s: ParseFEN(%q) succeeded", name, fen)
+		}
+	}
+}
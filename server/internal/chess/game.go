@@ -0,0 +1,201 @@
+package chess
+
+import "strings"
+
+const (
+	ResultWhiteWins = "1-0"
+	ResultBlackWins = "0-1"
+	ResultDraw      = "1/2-1/2"
+	ResultOngoing   = "*"
+)
+
+// Termination reasons detected from the position itself. The strings match
+// the values stored in models.Game.Reason.
+const (
+	ReasonCheckmate            = "checkmate"
+	ReasonStalemate            = "stalemate"
+	ReasonInsufficientMaterial = "insufficient_material"
+	ReasonThreefoldRepetition  = "threefold_repetition"
+	ReasonFiftyMoveRule        = "fifty_move_rule"
+)
+
+type Outcome struct {
+	Result string
+	Reason string
+}
+
+// Winner returns "white", "black" or "" for a draw or unfinished game.
+func (o Outcome) Winner() string {
+	switch o.Result {
+	case ResultWhiteWins:
+		return White.String()
+	case ResultBlackWins:
+		return Black.String()
+	}
+	return ""
+}
+
+// WinResult returns the result string for a win by c.
+func WinResult(c Color) string {
+	if c == White {
+		return ResultWhiteWins
+	}
+	return ResultBlackWins
+}
+
+// Game is a sequence of positions reached from a starting position. Unlike
+// Position it remembers history, which threefold repetition needs.
+type Game struct {
+	positions []*Position
+	moves     []Move
+}
+
+func NewGame(start *Position) *Game {
+	return &Game{positions: []*Position{start}}
+}
+
+// Position returns the current position.
+func (g *Game) Position() *Position {
+	return g.positions[len(g.positions)-1]
+}
+
+func (g *Game) Moves() []Move {
+	return g.moves
+}
+
+// SANHistory returns the moves played so far in Standard Algebraic Notation.
+func (g *Game) SANHistory() []string {
+	sans := make([]string, len(g.moves))
+	for i, m := range g.moves {
+		sans[i] = g.positions[i].SAN(m)
+	}
+	return sans
+}
+
+// PlayUCI validates a UCI move against the current position and plays it.
+func (g *Game) PlayUCI(uci string) (Move, error) {
+	m, err := g.Position().ParseMove(uci)
+	if err != nil {
+		return Move{}, err
+	}
+	g.Play(m)
+	return m, nil
+}
+
+// Play appends m without checking legality.
+func (g *Game) Play(m Move) {
+	g.positions = append(g.positions, g.Position().Play(m))
+	g.moves = append(g.moves, m)
+}
+
+// Undo takes back the last move. It reports false if there was none.
+func (g *Game) Undo() bool {
+	if len(g.moves) == 0 {
+		return false
+	}
+	g.positions = g.positions[:len(g.positions)-1]
+	g.moves = g.moves[:len(g.moves)-1]
+	return true
+}
+
+// Outcome reports whether the game has ended by rule in the current
+// position. Threefold repetition and the fifty-move rule are applied
+// automatically rather than waiting for a claim.
+func (g *Game) Outcome() (Outcome, bool) {
+	p := g.Position()
+
+	if len(p.LegalMoves()) == 0 {
+		if p.InCheck() {
+			return Outcome{Result: WinResult(p.turn.Other()), Reason: ReasonCheckmate}, true
+		}
+		return Outcome{Result: ResultDraw, Reason: ReasonStalemate}, true
+	}
+	if p.InsufficientMaterial() {
+		return Outcome{Result: ResultDraw, Reason: ReasonInsufficientMaterial}, true
+	}
+	if g.repetitions() >= 3 {
+		return Outcome{Result: ResultDraw, Reason: ReasonThreefoldRepetition}, true
+	}
+	if p.halfmoveClock >= 100 {
+		return Outcome{Result: ResultDraw, Reason: ReasonFiftyMoveRule}, true
+	}
+	return Outcome{Result: ResultOngoing}, false
+}
+
+// repetitions counts how often the current position has occurred. Only
+// positions since the last capture or pawn move can repeat.
+func (g *Game) repetitions() int {
+	current := g.Position()
+	key := current.repetitionKey()
+	count := 0
+	for i := len(g.positions) - 1; i >= 0 && i >= len(g.positions)-1-current.halfmoveClock; i-- {
+		if g.positions[i].repetitionKey() == key {
+			count++
+		}
+	}
+	return count
+}
+
+// repetitionKey identifies a position for repetition purposes: placement,
+// side to move, castling rights and an en passant square only when the
+// capture is actually available.
+func (p *Position) repetitionKey() string {
+	fields := strings.Fields(p.FEN())
+	if p.enPassant != NoSquare {
+		available := false
+		for _, m := range p.LegalMoves() {
+			if m.To == p.enPassant && p.board[m.From].Type() == Pawn {
+				available = true
+				break
+			}
+		}
+		if !available {
+			fields[3] = "-"
+		}
+	}
+	return strings.Join(fields[:4], " ")
+}
+
+// InsufficientMaterial reports whether neither side can possibly mate:
+// bare kings, a single minor piece, or only bishops all on one square color.
+func (p *Position) InsufficientMaterial() bool {
+	minors := 0
+	bishopColors := [2]bool{}
+	bishopsOnly := true
+	for sq := Square(0); sq < 64; sq++ {
+		switch p.board[sq].Type() {
+		case Pawn, Rook, Queen:
+			return false
+		case Knight:
+			minors++
+			bishopsOnly = false
+		case Bishop:
+			minors++
+			bishopColors[(sq.File()+sq.Rank())%2] = true
+		}
+	}
+	if minors <= 1 {
+		return true
+	}
+	return bishopsOnly && !(bishopColors[0] && bishopColors[1])
+}
+
+// HasMatingMaterial reports whether c has more than a bare king or a king
+// and a single minor piece. It decides whether running out of time against
+// c loses or only draws.
+func (p *Position) HasMatingMaterial(c Color) bool {
+	minors := 0
+	for sq := Square(0); sq < 64; sq++ {
+		piece := p.board[sq]
+		if piece == NoPiece || piece.Color() != c {
+			continue
+		}
+		switch piece.Type() {
+		case Pawn, Rook, Queen:
+			return true
+		case Knight, Bishop:
+			minors++
+		}
+	}
+	return minors > 1
+}
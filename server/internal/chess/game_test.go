@@ -0,0 +1,57 @@
+package chess
+
+import "testing"
+
+func playAll(t *testing.T, fen string, moves ...string) *Game {
+	t.Helper()
+	p, err := ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(p)
+	for _, uci := range moves {
+		if _, err := g.PlayUCI(uci); err != nil {
+			t.Fatalf("%s: %v", uci, err)
+		}
+	}
+	return g
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		name   string
+		game   *Game
+		result string
+		reason string
+	}{
+		{"checkmate", playAll(t, StartFEN, "f2f3", "e7e5", "g2g4", "d8h4"), ResultBlackWins, ReasonCheckmate},
+		{"stalemate", playAll(t, "7k/8/6Q1/8/8/8/8/K7 w - - 0 1", "g6f7"), ResultDraw, ReasonStalemate},
+		{"insufficient material", playAll(t, "4k3/8/8/8/8/8/3r4/4KB2 w - - 0 1", "e1d2"), ResultDraw, ReasonInsufficientMaterial},
+		{"threefold repetition", playAll(t, StartFEN, "g1f3", "g8f6", "f3g1", "f6g8", "g1f3", "g8f6", "f3g1", "f6g8"), ResultDraw, ReasonThreefoldRepetition},
+		{"fifty-move rule", playAll(t, "4k3/8/8/8/8/8/8/R3K3 w - - 99 80", "a1a2"), ResultDraw, ReasonFiftyMoveRule},
+	}
+	for _, tt := range tests {
+		o, over := tt.game.Outcome()
+		if !over || o.Result != tt.result || o.Reason != tt.reason {
+			t.Errorf("%s: Outcome() = %+v, %v", tt.name, o, over)
+		}
+	}
+
+	if o, over := playAll(t, StartFEN, "e2e4").Outcome(); over || o.Result != ResultOngoing {
+		t.Errorf("ongoing game: Outcome() = %+v, %v", o, over)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	g := playAll(t, StartFEN, "e2e4", "e7e5")
+	if !g.Undo() || g.Position().FEN() != "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1" {
+		t.Errorf("after Undo: %s", g.Position().FEN())
+	}
+	if got := g.SANHistory(); len(got) != 1 || got[0] != "e4" {
+		t.Errorf("SANHistory() = %v", got)
+	}
+	g.Undo()
+	if g.Undo() {
+		t.Error("Undo with no moves reported true")
+	}
+}
@@ -0,0 +1,56 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrIllegalMove = errors.New("illegal move")
+
+type Move struct {
+	From      Square
+	To        Square
+	Promotion PieceType
+}
+
+// String returns the move in UCI long algebraic notation, e.g. "e2e4" or "e7e8q".
+func (m Move) String() string {
+	s := m.From.String() + m.To.String()
+	if m.Promotion != NoPieceType {
+		s += string(pieceLetters[m.Promotion])
+	}
+	return s
+}
+
+// ParseUCI decodes a UCI move string. It only checks the syntax; use
+// Position.ParseMove to also check that the move is legal.
+func ParseUCI(s string) (Move, error) {
+	if len(s) != 4 && len(s) != 5 {
+		return Move{}, fmt.Errorf("invalid uci move %q", s)
+	}
+	from, err := ParseSquare(s[0:2])
+	if err != nil {
+		return Move{}, err
+	}
+	to, err := ParseSquare(s[2:4])
+	if err != nil {
+		return Move{}, err
+	}
+
+	m := Move{From: from, To: to}
+	if len(s) == 5 {
+		switch s[4] {
+		case 'q':
+			m.Promotion = Queen
+		case 'r':
+			m.Promotion = Rook
+		case 'b':
+			m.Promotion = Bishop
+		case 'n':
+			m.Promotion = Knight
+		default:
+			return Move{}, fmt.Errorf("invalid promotion in uci move %q", s)
+		}
+	}
+	return m, nil
+}
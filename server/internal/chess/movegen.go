@@ -0,0 +1,238 @@
+package chess
+
+import "fmt"
+
+var (
+	knightOffsets = [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingOffsets   = [8][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	bishopDirs    = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	rookDirs      = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+)
+
+var promotionTypes = [4]PieceType{Queen, Rook, Bishop, Knight}
+
+// offset returns the square reached from sq by moving df files and dr
+// ranks, or NoSquare if that leaves the board.
+func offset(sq Square, df, dr int) Square {
+	f, r := sq.File()+df, sq.Rank()+dr
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return NoSquare
+	}
+	return NewSquare(f, r)
+}
+
+// isAttacked reports whether any piece of color by attacks sq.
+func (p *Position) isAttacked(sq Square, by Color) bool {
+	// Pawns attack diagonally forward, so look one rank "behind" sq from
+	// the attacker's point of view.
+	dr := -1
+	if by == Black {
+		dr = 1
+	}
+	for _, df := range []int{-1, 1} {
+		if s := offset(sq, df, dr); s != NoSquare && p.board[s] == NewPiece(by, Pawn) {
+			return true
+		}
+	}
+
+	for _, o := range knightOffsets {
+		if s := offset(sq, o[0], o[1]); s != NoSquare && p.board[s] == NewPiece(by, Knight) {
+			return true
+		}
+	}
+	for _, o := range kingOffsets {
+		if s := offset(sq, o[0], o[1]); s != NoSquare && p.board[s] == NewPiece(by, King) {
+			return true
+		}
+	}
+
+	if p.slidingAttack(sq, by, bishopDirs[:], Bishop) || p.slidingAttack(sq, by, rookDirs[:], Rook) {
+		return true
+	}
+	return false
+}
+
+func (p *Position) slidingAttack(sq Square, by Color, dirs [][2]int, slider PieceType) bool {
+	for _, d := range dirs {
+		for s := offset(sq, d[0], d[1]); s != NoSquare; s = offset(s, d[0], d[1]) {
+			piece := p.board[s]
+			if piece == NoPiece {
+				continue
+			}
+			if piece.Color() == by && (piece.Type() == slider || piece.Type() == Queen) {
+				return true
+			}
+			break
+		}
+	}
+	return false
+}
+
+// LegalMoves returns every legal move for the side to move.
+func (p *Position) LegalMoves() []Move {
+	pseudo := p.pseudoLegalMoves()
+	legal := pseudo[:0]
+	for _, m := range pseudo {
+		next := p.Play(m)
+		if !next.isAttacked(next.kingSquare(p.turn), next.turn) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+// IsLegal reports whether m is a legal move in this position.
+func (p *Position) IsLegal(m Move) bool {
+	for _, lm := range p.LegalMoves() {
+		if lm == m {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMove decodes a UCI move string and checks it is legal here.
+func (p *Position) ParseMove(uci string) (Move, error) {
+	m, err := ParseUCI(uci)
+	if err != nil {
+		return Move{}, err
+	}
+	if !p.IsLegal(m) {
+		return Move{}, fmt.Errorf("%w: %s", ErrIllegalMove, uci)
+	}
+	return m, nil
+}
+
+func (p *Position) pseudoLegalMoves() []Move {
+	moves := make([]Move, 0, 48)
+	for sq := Square(0); sq < 64; sq++ {
+		piece := p.board[sq]
+		if piece == NoPiece || piece.Color() != p.turn {
+			continue
+		}
+		switch piece.Type() {
+		case Pawn:
+			moves = p.pawnMoves(moves, sq)
+		case Knight:
+			moves = p.stepMoves(moves, sq, knightOffsets[:])
+		case Bishop:
+			moves = p.slideMoves(moves, sq, bishopDirs[:])
+		case Rook:
+			moves = p.slideMoves(moves, sq, rookDirs[:])
+		case Queen:
+			moves = p.slideMoves(moves, sq, bishopDirs[:])
+			moves = p.slideMoves(moves, sq, rookDirs[:])
+		case King:
+			moves = p.stepMoves(moves, sq, kingOffsets[:])
+			moves = p.castlingMoves(moves, sq)
+		}
+	}
+	return moves
+}
+
+func (p *Position) pawnMoves(moves []Move, from Square) []Move {
+	dir, startRank, lastRank := 1, 1, 7
+	if p.turn == Black {
+		dir, startRank, lastRank = -1, 6, 0
+	}
+
+	add := func(to Square) {
+		if to.Rank() == lastRank {
+			for _, pt := range promotionTypes {
+				moves = append(moves, Move{From: from, To: to, Promotion: pt})
+			}
+			return
+		}
+		moves = append(moves, Move{From: from, To: to})
+	}
+
+	if one := offset(from, 0, dir); one != NoSquare && p.board[one] == NoPiece {
+		add(one)
+		if from.Rank() == startRank {
+			if two := offset(from, 0, 2*dir); p.board[two] == NoPiece {
+				add(two)
+			}
+		}
+	}
+
+	for _, df := range []int{-1, 1} {
+		to := offset(from, df, dir)
+		if to == NoSquare {
+			continue
+		}
+		target := p.board[to]
+		if (target != NoPiece && target.Color() != p.turn) || to == p.enPassant {
+			add(to)
+		}
+	}
+	return moves
+}
+
+func (p *Position) stepMoves(moves []Move, from Square, offsets [][2]int) []Move {
+	for _, o := range offsets {
+		to := offset(from, o[0], o[1])
+		if to == NoSquare {
+			continue
+		}
+		if target := p.board[to]; target == NoPiece || target.Color() != p.turn {
+			moves = append(moves, Move{From: from, To: to})
+		}
+	}
+	return moves
+}
+
+func (p *Position) slideMoves(moves []Move, from Square, dirs [][2]int) []Move {
+	for _, d := range dirs {
+		for to := offset(from, d[0], d[1]); to != NoSquare; to = offset(to, d[0], d[1]) {
+			target := p.board[to]
+			if target == NoPiece {
+				moves = append(moves, Move{From: from, To: to})
+				continue
+			}
+			if target.Color() != p.turn {
+				moves = append(moves, Move{From: from, To: to})
+			}
+			break
+		}
+	}
+	return moves
+}
+
+func (p *Position) castlingMoves(moves []Move, from Square) []Move {
+	rank, kingSide, queenSide := 0, WhiteKingSide, WhiteQueenSide
+	if p.turn == Black {
+		rank, kingSide, queenSide = 7, BlackKingSide, BlackQueenSide
+	}
+	if from != NewSquare(4, rank) || p.isAttacked(from, p.turn.Other()) {
+		return moves
+	}
+	rook := NewPiece(p.turn, Rook)
+
+	if p.castling&kingSide != 0 && p.board[NewSquare(7, rank)] == rook &&
+		p.empty(rank, 5, 6) && !p.attackedAny(rank, p.turn.Other(), 5, 6) {
+		moves = append(moves, Move{From: from, To: NewSquare(6, rank)})
+	}
+	if p.castling&queenSide != 0 && p.board[NewSquare(0, rank)] == rook &&
+		p.empty(rank, 1, 2, 3) && !p.attackedAny(rank, p.turn.Other(), 2, 3) {
+		moves = append(moves, Move{From: from, To: NewSquare(2, rank)})
+	}
+	return moves
+}
+
+func (p *Position) empty(rank int, files ...int) bool {
+	for _, f := range files {
+		if p.board[NewSquare(f, rank)] != NoPiece {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Position) attackedAny(rank int, by Color, files ...int) bool {
+	for _, f := range files {
+		if p.isAttacked(NewSquare(f, rank), by) {
+			return true
+		}
+	}
+	return false
+}
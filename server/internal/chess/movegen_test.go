@@ -0,0 +1,134 @@
+package chess
+
+import "testing"
+
+func perft(p *Position, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+	moves := p.LegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+	n := 0
+	for _, m := range moves {
+		n += perft(p.Play(m), depth-1)
+	}
+	return n
+}
+
+func TestPerft(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		depth int
+		nodes int
+	}{
+		{"start", StartFEN, 4, 197281},
+		{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 3, 97862},
+		{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 4, 43238},
+		{"position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", 3, 9467},
+	}
+	for _, tt := range tests {
+		if testing.Short() && tt.nodes > 50000 {
+			continue
+		}
+		p, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := perft(p, tt.depth); got != tt.nodes {
+			t.Errorf("%s: perft(%d) = %d, want %d", tt.name, tt.depth, got, tt.nodes)
+		}
+	}
+}
+
+// play parses and plays uci on fen, failing the test if it isn't legal.
+func play(t *testing.T, fen, uci string) *Position {
+	t.Helper()
+	p, err := ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := p.ParseMove(uci)
+	if err != nil {
+		t.Fatalf("%s on %q: %v", uci, fen, err)
+	}
+	return p.Play(m)
+}
+
+func TestCastling(t *testing.T) {
+	const fen = "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1"
+	if got := play(t, fen, "e1g1").FEN(); got != "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1" {
+		t.Errorf("O-O: %s", got)
+	}
+	if got := play(t, fen, "e1c1").FEN(); got != "r3k2r/8/8/8/8/8/8/2KR3R b kq - 1 1" {
+		t.Errorf("O-O-O: %s", got)
+	}
+	// Moving a rook gives up castling on its side only.
+	if got := play(t, fen, "h1h2").CastlingRights().String(); got != "Qkq" {
+		t.Errorf("rights after Rh2 = %s", got)
+	}
+	// Capturing a rook takes away its owner's right too.
+	if got := play(t, fen, "a1a8").CastlingRights().String(); got != "Kk" {
+		t.Errorf("rights after Rxa8 = %s", got)
+	}
+
+	illegal := map[string]string{
+		"through check": "r3k2r/8/8/8/8/8/5r2/R3K2R w KQkq - 0 1",
+		"out of check":  "r3k2r/8/8/8/8/8/4r3/R3K2R w KQkq - 0 1",
+		"no right":      "r3k2r/8/8/8/8/8/8/R3K2R w Qkq - 0 1",
+		"blocked":       "r3k2r/8/8/8/8/8/8/R3K1NR w KQkq - 0 1",
+	}
+	for name, fen := range illegal {
+		p, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := p.ParseMove("e1g1"); err == nil {
+			t.Errorf("%s: O-O allowed", name)
+		}
+	}
+}
+
+func TestEnPassant(t *testing.T) {
+	p := play(t, "4k3/3p4/8/4P3/8/8/8/4K3 b - - 0 1", "d7d5")
+	if p.EnPassant().String() != "d6" {
+		t.Fatalf("en passant square = %v", p.EnPassant())
+	}
+	m, err := p.ParseMove("e5d6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Play(m).FEN(); got != "4k3/8/3P4/8/8/8/8/4K3 b - - 0 2" {
+		t.Errorf("after exd6: %s", got)
+	}
+
+	// Taking en passant may not expose the king along the rank.
+	p = play(t, "8/8/8/8/k2p3R/8/4P3/4K3 w - - 0 1", "e2e4")
+	if _, err := p.ParseMove("d4e3"); err == nil {
+		t.Error("en passant exposing the king allowed")
+	}
+}
+
+func TestPromotion(t *testing.T) {
+	p, err := ParseFEN("1n2k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, m := range p.LegalMoves() {
+		if m.Promotion != NoPieceType {
+			count++
+		}
+	}
+	if count != 8 {
+		t.Errorf("%d promotions, want 8 (two squares, four pieces)", count)
+	}
+	if _, err := p.ParseMove("a7a8"); err == nil {
+		t.Error("promotion without a piece allowed")
+	}
+	if got := play(t, p.FEN(), "a7b8n").FEN(); got != "1N2k3/8/8/8/8/8/8/4K3 b - - 0 1" {
+		t.Errorf("after axb8=N: %s", got)
+	}
+}
@@ -0,0 +1,267 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+type PGNTag struct {
+	Name  string
+	Value string
+}
+
+// PGNGame is one game in Portable Game Notation: tag pairs plus SAN
+// movetext. Comments, if set, holds one (possibly empty) comment per move.
+type PGNGame struct {
+	Tags     []PGNTag
+	Moves    []string
+	Comments []string
+	Result   string
+}
+
+// Tag returns the value of the named tag, or "" if it is absent.
+func (g *PGNGame) Tag(name string) string {
+	for _, t := range g.Tags {
+		if t.Name == name {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+const pgnLineWidth = 80
+
+// String renders the game in PGN export format: tags, a blank line and the
+// movetext wrapped at 80 columns.
+func (g *PGNGame) String() string {
+	var sb strings.Builder
+	for _, t := range g.Tags {
+		value := strings.ReplaceAll(t.Value, `\`, `\\`)
+		value = strings.ReplaceAll(value, `"`, `\"`)
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", t.Name, value)
+	}
+	sb.WriteByte('\n')
+
+	// Move numbers continue from a custom starting position if there is one.
+	moveNumber, turn := 1, White
+	if fen := g.Tag("FEN"); fen != "" {
+		if p, err := ParseFEN(fen); err == nil {
+			moveNumber, turn = p.fullmoveNumber, p.turn
+		}
+	}
+
+	var tokens []string
+	for i, san := range g.Moves {
+		switch {
+		case turn == White:
+			tokens = append(tokens, fmt.Sprintf("%d.", moveNumber))
+		case i == 0 || (g.Comments != nil && g.Comments[i-1] != ""):
+			tokens = append(tokens, fmt.Sprintf("%d...", moveNumber))
+		}
+		tokens = append(tokens, san)
+		if g.Comments != nil && g.Comments[i] != "" {
+			tokens = append(tokens, "{ "+g.Comments[i]+" }")
+		}
+		if turn == Black {
+			moveNumber++
+		}
+		turn = turn.Other()
+	}
+	result := g.Result
+	if result == "" {
+		result = ResultOngoing
+	}
+	tokens = append(tokens, result)
+
+	lineLen := 0
+	for _, tok := range tokens {
+		if lineLen > 0 && lineLen+1+len(tok) > pgnLineWidth {
+			sb.WriteByte('\n')
+			lineLen = 0
+		} else if lineLen > 0 {
+			sb.WriteByte(' ')
+			lineLen++
+		}
+		sb.WriteString(tok)
+		lineLen += len(tok)
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+// ParsePGN reads every game in a PGN file. Moves are returned as written;
+// comments are kept per move and variations and NAGs are skipped.
+func ParsePGN(text string) ([]*PGNGame, error) {
+	var games []*PGNGame
+	var cur *PGNGame
+	inMovetext := false
+
+	start := func() {
+		if cur == nil {
+			cur = &PGNGame{}
+		}
+	}
+	finish := func(result string) {
+		if cur == nil {
+			return
+		}
+		if result == "" {
+			result = cur.Tag("Result")
+		}
+		cur.Result = result
+		games = append(games, cur)
+		cur = nil
+		inMovetext = false
+	}
+
+	i := 0
+	for i < len(text) {
+		ch := text[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			i++
+
+		case ch == '%' && (i == 0 || text[i-1] == '\n'):
+			// Escape line: ignored to the end of the line.
+			i = skipLine(text, i)
+
+		case ch == ';':
+			i = skipLine(text, i)
+
+		case ch == '[' && !inMovetext:
+			end := tagEnd(text[i:])
+			if end < 0 {
+				return nil, fmt.Errorf("pgn: unterminated tag at offset %d", i)
+			}
+			tag, err := parseTag(text[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			start()
+			cur.Tags = append(cur.Tags, tag)
+			i += end + 1
+
+		case ch == '[':
+			// A tag after movetext without a result: the previous game ended.
+			finish("")
+
+		case ch == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("pgn: unterminated comment at offset %d", i)
+			}
+			if cur != nil && len(cur.Moves) > 0 {
+				comment := strings.TrimSpace(text[i+1 : i+end])
+				last := len(cur.Moves) - 1
+				if cur.Comments[last] != "" {
+					comment = cur.Comments[last] + " " + comment
+				}
+				cur.Comments[last] = comment
+			}
+			i += end + 1
+
+		case ch == '(':
+			depth := 0
+			for ; i < len(text); i++ {
+				if text[i] == '(' {
+					depth++
+				} else if text[i] == ')' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if depth != 0 {
+				return nil, fmt.Errorf("pgn: unterminated variation")
+			}
+			i++
+
+		case ch == ')' || ch == '}' || ch == ']':
+			return nil, fmt.Errorf("pgn: unexpected %q at offset %d", ch, i)
+
+		default:
+			j := i
+			for j < len(text) && !strings.ContainsRune(" \t\r\n{}()[];", rune(text[j])) {
+				j++
+			}
+			tok := text[i:j]
+			i = j
+
+			switch {
+			case tok == ResultWhiteWins || tok == ResultBlackWins || tok == ResultDraw || tok == ResultOngoing:
+				start()
+				finish(tok)
+			case tok[0] == '$' || tok == "e.p.":
+				// NAG or en passant marker
+			default:
+				// Strip move numbers ("12." and "12...") glued to the move.
+				if digits := strings.TrimLeft(tok, "0123456789"); digits != tok && strings.HasPrefix(digits, ".") {
+					tok = strings.TrimLeft(digits, ".")
+				}
+				if tok == "" {
+					continue
+				}
+				start()
+				inMovetext = true
+				cur.Moves = append(cur.Moves, tok)
+				cur.Comments = append(cur.Comments, "")
+			}
+		}
+	}
+	finish("")
+
+	if len(games) == 0 {
+		return nil, fmt.Errorf("pgn: no games found")
+	}
+	return games, nil
+}
+
+func skipLine(text string, i int) int {
+	if end := strings.IndexByte(text[i:], '\n'); end >= 0 {
+		return i + end + 1
+	}
+	return len(text)
+}
+
+// tagEnd returns the offset of the "]" closing the tag that starts s,
+// ignoring brackets inside the quoted value.
+func tagEnd(s string) int {
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ']':
+			if !quoted {
+				return i
+			}
+		case '\n':
+			return -1
+		}
+	}
+	return -1
+}
+
+func parseTag(body string) (PGNTag, error) {
+	body = strings.TrimSpace(body)
+	name, rest, ok := strings.Cut(body, " ")
+	rest = strings.TrimSpace(rest)
+	if !ok || len(rest) < 2 || rest[0] != '"' || rest[len(rest)-1] != '"' {
+		return PGNTag{}, fmt.Errorf("pgn: invalid tag [%s]", body)
+	}
+	value := strings.ReplaceAll(rest[1:len(rest)-1], `\"`, `"`)
+	value = strings.ReplaceAll(value, `\\`, `\`)
+	return PGNTag{Name: name, Value: value}, nil
+}
+
+// StartPosition returns the position the movetext starts from, honouring
+// the FEN tag when present.
+func (g *PGNGame) StartPosition() (*Position, error) {
+	if fen := g.Tag("FEN"); fen != "" {
+		return ParseFEN(fen)
+	}
+	return StartingPosition(), nil
+}
@@ -0,0 +1,101 @@
+package chess
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePGN(t *testing.T) {
+	const text = `[Event "Casual game"]
+[White "Alice \"A\""]
+[Black "Bob"]
+[Result "1-0"]
+
+1. e4 {King's pawn} e5 2. Nf3 $1 (2. f4 exf4 (2... d5) 3. Nf3) 2... Nc6!
+; a rest-of-line comment
+3. Bb5 a6 {Morphy} {Defence} 4.Ba4 1-0
+
+% an escaped line
+[Event "Second"]
+[Result "*"]
+
+1. d4 d5 *
+`
+	games, err := ParsePGN(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("got %d games, want 2", len(games))
+	}
+
+	g := games[0]
+	if g.Tag("White") != `Alice "A"` || g.Tag("Black") != "Bob" || g.Tag("Missing") != "" {
+		t.Errorf("tags = %+v", g.Tags)
+	}
+	wantMoves := []string{"e4", "e5", "Nf3", "Nc6!", "Bb5", "a6", "Ba4"}
+	if !reflect.DeepEqual(g.Moves, wantMoves) {
+		t.Errorf("moves = %v, want %v", g.Moves, wantMoves)
+	}
+	wantComments := []string{"King's pawn", "", "", "", "", "Morphy Defence", ""}
+	if !reflect.DeepEqual(g.Comments, wantComments) {
+		t.Errorf("comments = %q, want %q", g.Comments, wantComments)
+	}
+	if g.Result != ResultWhiteWins {
+		t.Errorf("result = %q", g.Result)
+	}
+
+	g = games[1]
+	if g.Tag("Event") != "Second" || !reflect.DeepEqual(g.Moves, []string{"d4", "d5"}) || g.Result != ResultOngoing {
+		t.Errorf("second game = %+v", g)
+	}
+}
+
+func TestParsePGNWithoutResultToken(t *testing.T) {
+	games, err := ParsePGN("[Result \"1/2-1/2\"]\n\n1. e4 e5\n[Event \"Next\"]\n\n1. c4 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 || games[0].Result != ResultDraw || len(games[1].Moves) != 1 {
+		t.Errorf("games = %+v %+v", games[0], games[1])
+	}
+}
+
+func TestParsePGNRejectsMalformed(t *testing.T) {
+	inputs := map[string]string{
+		"stray paren":            "1. e4 ) e5 *",
+		"stray brace":            "1. e4 } e5 *",
+		"stray bracket":          "1. e4 ] e5 *",
+		"unterminated comment":   "1. e4 { e5 *",
+		"unterminated variation": "1. e4 (1. d4 *",
+		"unterminated tag":       "[Event \"x\n1. e4 *",
+		"bad tag":                "[Event x]\n1. e4 *",
+		"empty":                  "  \n",
+	}
+	for name, text := range inputs {
+		if _, err := ParsePGN(text); err == nil {
+			t.Errorf("%s: ParsePGN(%q) succeeded", name, text)
+		}
+	}
+}
+
+func TestPGNRoundTrip(t *testing.T) {
+	g := &PGNGame{
+		Tags:     []PGNTag{{"Event", "Test"}, {"FEN", "4k3/8/8/8/8/8/4P3/4K3 b - - 0 7"}},
+		Moves:    []string{"Kd7", "e4", "Ke6"},
+		Comments: []string{"", "push", ""},
+		Result:   ResultDraw,
+	}
+	text := g.String()
+	if !strings.Contains(text, "7... Kd7 8. e4 { push } 8... Ke6 1/2-1/2") {
+		t.Errorf("String() = %q", text)
+	}
+	parsed, err := ParsePGN(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed[0].Moves, g.Moves) || !reflect.DeepEqual(parsed[0].Comments, g.Comments) {
+		t.Errorf("round trip = %+v", parsed[0])
+	}
+}
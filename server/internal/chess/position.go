@@ -0,0 +1,133 @@
+package chess
+
+// Position is an immutable snapshot of a game: piece placement plus the
+// state FEN carries (side to move, castling, en passant and move counters).
+// Play returns a new Position, so values can be shared freely.
+type Position struct {
+	board          [64]Piece
+	turn           Color
+	castling       CastlingRights
+	enPassant      Square
+	halfmoveClock  int
+	fullmoveNumber int
+}
+
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// StartingPosition returns the standard initial position.
+func StartingPosition() *Position {
+	p, err := ParseFEN(StartFEN)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p *Position) PieceAt(sq Square) Piece {
+	return p.board[sq]
+}
+
+func (p *Position) Turn() Color {
+	return p.turn
+}
+
+func (p *Position) CastlingRights() CastlingRights {
+	return p.castling
+}
+
+func (p *Position) EnPassant() Square {
+	return p.enPassant
+}
+
+func (p *Position) HalfmoveClock() int {
+	return p.halfmoveClock
+}
+
+func (p *Position) FullmoveNumber() int {
+	return p.fullmoveNumber
+}
+
+func (p *Position) kingSquare(c Color) Square {
+	king := NewPiece(c, King)
+	for sq := Square(0); sq < 64; sq++ {
+		if p.board[sq] == king {
+			return sq
+		}
+	}
+	return NoSquare
+}
+
+// InCheck reports whether the side to move is in check.
+func (p *Position) InCheck() bool {
+	k := p.kingSquare(p.turn)
+	return k != NoSquare && p.isAttacked(k, p.turn.Other())
+}
+
+// Play applies m without checking legality and returns the resulting
+// position. Callers validating user input should go through ParseMove or
+// LegalMoves first.
+func (p *Position) Play(m Move) *Position {
+	next := *p
+	piece := p.board[m.From]
+	captured := p.board[m.To]
+
+	next.board[m.From] = NoPiece
+	next.board[m.To] = piece
+	next.enPassant = NoSquare
+
+	switch piece.Type() {
+	case Pawn:
+		if m.To == p.enPassant && captured == NoPiece {
+			// En passant: the captured pawn sits behind the target square.
+			next.board[NewSquare(m.To.File(), m.From.Rank())] = NoPiece
+		}
+		if d := int(m.To) - int(m.From); d == 16 || d == -16 {
+			next.enPassant = Square((int(m.To) + int(m.From)) / 2)
+		}
+		if m.Promotion != NoPieceType {
+			next.board[m.To] = NewPiece(piece.Color(), m.Promotion)
+		}
+	case King:
+		if d := m.To.File() - m.From.File(); d == 2 || d == -2 {
+			rank := m.From.Rank()
+			rookFrom, rookTo := NewSquare(7, rank), NewSquare(5, rank)
+			if d < 0 {
+				rookFrom, rookTo = NewSquare(0, rank), NewSquare(3, rank)
+			}
+			next.board[rookTo] = next.board[rookFrom]
+			next.board[rookFrom] = NoPiece
+		}
+	}
+
+	next.castling &^= castlingMask(m.From) | castlingMask(m.To)
+
+	if piece.Type() == Pawn || captured != NoPiece {
+		next.halfmoveClock = 0
+	} else {
+		next.halfmoveClock++
+	}
+	if p.turn == Black {
+		next.fullmoveNumber++
+	}
+	next.turn = p.turn.Other()
+	return &next
+}
+
+// castlingMask returns the rights lost when a piece moves from or to sq.
+func castlingMask(sq Square) CastlingRights {
+	switch sq {
+	case NewSquare(4, 0):
+		return WhiteKingSide | WhiteQueenSide
+	case NewSquare(7, 0):
+		return WhiteKingSide
+	case NewSquare(0, 0):
+		return WhiteQueenSide
+	case NewSquare(4, 7):
+		return BlackKingSide | BlackQueenSide
+	case NewSquare(7, 7):
+		return BlackKingSide
+	case NewSquare(0, 7):
+		return BlackQueenSide
+	}
+	return 0
+}
@@ -0,0 +1,162 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SAN renders a legal move in Standard Algebraic Notation, including the
+// minimal disambiguation and a "+" or "#" suffix.
+func (p *Position) SAN(m Move) string {
+	var sb strings.Builder
+	piece := p.board[m.From]
+
+	switch {
+	case piece.Type() == King && m.To.File()-m.From.File() == 2:
+		sb.WriteString("O-O")
+	case piece.Type() == King && m.From.File()-m.To.File() == 2:
+		sb.WriteString("O-O-O")
+	default:
+		capture := p.board[m.To] != NoPiece || (piece.Type() == Pawn && m.To == p.enPassant)
+		if piece.Type() == Pawn {
+			if capture {
+				sb.WriteByte(byte('a' + m.From.File()))
+			}
+		} else {
+			sb.WriteByte(NewPiece(White, piece.Type()).Letter())
+			sb.WriteString(p.disambiguation(m))
+		}
+		if capture {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(m.To.String())
+		if m.Promotion != NoPieceType {
+			sb.WriteByte('=')
+			sb.WriteByte(NewPiece(White, m.Promotion).Letter())
+		}
+	}
+
+	next := p.Play(m)
+	if next.InCheck() {
+		if len(next.LegalMoves()) == 0 {
+			sb.WriteByte('#')
+		} else {
+			sb.WriteByte('+')
+		}
+	}
+	return sb.String()
+}
+
+// disambiguation returns the file, rank or square needed to tell m apart
+// from other legal moves of the same piece type to the same square.
+func (p *Position) disambiguation(m Move) string {
+	piece := p.board[m.From]
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, other := range p.LegalMoves() {
+		if other.To != m.To || other.From == m.From || p.board[other.From] != piece {
+			continue
+		}
+		ambiguous = true
+		if other.From.File() == m.From.File() {
+			sameFile = true
+		}
+		if other.From.Rank() == m.From.Rank() {
+			sameRank = true
+		}
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return string(rune('a' + m.From.File()))
+	case !sameRank:
+		return string(rune('1' + m.From.Rank()))
+	default:
+		return m.From.String()
+	}
+}
+
+// ParseSAN finds the legal move described by a SAN string. It tolerates the
+// usual variations found in the wild: annotation glyphs, "0-0" castling,
+// promotions without "=", and over-specified origin squares.
+func (p *Position) ParseSAN(san string) (Move, error) {
+	s := strings.TrimRight(san, "+#!?")
+	s = strings.ReplaceAll(s, "0", "O")
+
+	if s == "O-O" || s == "O-O-O" {
+		file := 6
+		if s == "O-O-O" {
+			file = 2
+		}
+		for _, m := range p.LegalMoves() {
+			if p.board[m.From].Type() == King && m.From.File() == 4 && m.To.File() == file {
+				return m, nil
+			}
+		}
+		return Move{}, fmt.Errorf("%w: %s", ErrIllegalMove, san)
+	}
+
+	pieceType := Pawn
+	if len(s) > 0 && strings.IndexByte("NBRQK", s[0]) >= 0 {
+		piece, _ := pieceFromLetter(s[0])
+		pieceType = piece.Type()
+		s = s[1:]
+	}
+
+	promotion := NoPieceType
+	if pieceType == Pawn && len(s) > 0 && strings.IndexByte("NBRQ", s[len(s)-1]) >= 0 {
+		piece, _ := pieceFromLetter(s[len(s)-1])
+		promotion = piece.Type()
+		s = strings.TrimSuffix(s[:len(s)-1], "=")
+	}
+
+	s = strings.NewReplacer("x", "", "-", "", ":", "").Replace(s)
+	if len(s) < 2 {
+		return Move{}, fmt.Errorf("invalid san %q", san)
+	}
+	to, err := ParseSquare(s[len(s)-2:])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid san %q", san)
+	}
+	hint := s[:len(s)-2]
+
+	var found []Move
+	for _, m := range p.LegalMoves() {
+		if m.To != to || m.Promotion != promotion || p.board[m.From].Type() != pieceType {
+			continue
+		}
+		if !matchesHint(m.From, hint) {
+			continue
+		}
+		found = append(found, m)
+	}
+	switch len(found) {
+	case 1:
+		return found[0], nil
+	case 0:
+		return Move{}, fmt.Errorf("%w: %s", ErrIllegalMove, san)
+	default:
+		return Move{}, fmt.Errorf("ambiguous move %q", san)
+	}
+}
+
+// matchesHint reports whether from agrees with a SAN disambiguation hint,
+// which may be a file, a rank, both, or empty.
+func matchesHint(from Square, hint string) bool {
+	for i := 0; i < len(hint); i++ {
+		switch ch := hint[i]; {
+		case ch >= 'a' && ch <= 'h':
+			if from.File() != int(ch-'a') {
+				return false
+			}
+		case ch >= '1' && ch <= '8':
+			if from.Rank() != int(ch-'1') {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
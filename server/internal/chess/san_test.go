@@ -0,0 +1,75 @@
+package chess
+
+import "testing"
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		fen, uci, san string
+	}{
+		{StartFEN, "g1f3", "Nf3"},
+		{StartFEN, "e2e4", "e4"},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "e1g1", "O-O"},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "e1c1", "O-O-O"},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "e4d5", "exd5"},
+		{"1k6/8/8/8/8/8/4K3/R6R w - - 0 1", "a1d1", "Rad1"},
+		{"4k3/8/8/8/8/R7/8/R3K3 w - - 0 1", "a1a2", "R1a2"},
+		{"k7/8/8/8/8/2Q1Q3/8/2Q1K3 w - - 0 1", "c3d2", "Qc3d2"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8q", "a8=Q+"},
+		{"6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1", "a1a8", "Ra8#"},
+	}
+	for _, tt := range tests {
+		p, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := p.ParseMove(tt.uci)
+		if err != nil {
+			t.Fatalf("%s on %q: %v", tt.uci, tt.fen, err)
+		}
+		if got := p.SAN(m); got != tt.san {
+			t.Errorf("SAN(%s) = %q, want %q", tt.uci, got, tt.san)
+		}
+		back, err := p.ParseSAN(tt.san)
+		if err != nil || back != m {
+			t.Errorf("ParseSAN(%q) = %v, %v, want %v", tt.san, back, err, m)
+		}
+	}
+}
+
+func TestParseSANVariants(t *testing.T) {
+	tests := []struct {
+		fen, san, uci string
+	}{
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "0-0", "e1g1"},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "0-0-0", "e1c1"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8Q", "a7a8q"},
+		{StartFEN, "Ng1f3", "g1f3"},
+		{StartFEN, "e4!?", "e2e4"},
+	}
+	for _, tt := range tests {
+		p, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := p.ParseSAN(tt.san)
+		if err != nil {
+			t.Errorf("ParseSAN(%q): %v", tt.san, err)
+			continue
+		}
+		if m.String() != tt.uci {
+			t.Errorf("ParseSAN(%q) = %s, want %s", tt.san, m, tt.uci)
+		}
+	}
+
+	p := StartingPosition()
+	for _, san := range []string{"e5", "Nf6", "Ke2", "O-O", "Rd1", "x"} {
+		if _, err := p.ParseSAN(san); err == nil {
+			t.Errorf("ParseSAN(%q) accepted", san)
+		}
+	}
+	// Ambiguous without a hint.
+	p, _ = ParseFEN("1k6/8/8/8/8/8/4K3/R6R w - - 0 1")
+	if _, err := p.ParseSAN("Rd1"); err == nil {
+		t.Error("ambiguous Rd1 accepted")
+	}
+}
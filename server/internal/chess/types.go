@@ -0,0 +1,145 @@
+package chess
+
+import "fmt"
+
+type Color int8
+
+const (
+	White Color = iota
+	Black
+)
+
+func (c Color) Other() Color {
+	return c ^ 1
+}
+
+func (c Color) String() string {
+	if c == White {
+		return "white"
+	}
+	return "black"
+}
+
+type PieceType int8
+
+const (
+	NoPieceType PieceType = iota
+	Pawn
+	Knight
+	Bishop
+	Rook
+	Queen
+	King
+)
+
+// Piece packs a PieceType in the low three bits and the Color above them,
+// so the zero value is an empty square.
+type Piece int8
+
+const NoPiece Piece = 0
+
+func NewPiece(c Color, t PieceType) Piece {
+	return Piece(int8(t) | int8(c)<<3)
+}
+
+func (p Piece) Type() PieceType {
+	return PieceType(p & 7)
+}
+
+func (p Piece) Color() Color {
+	return Color(p >> 3)
+}
+
+const pieceLetters = " pnbrqk"
+
+// Letter returns the FEN letter for the piece (uppercase for white).
+func (p Piece) Letter() byte {
+	l := pieceLetters[p.Type()]
+	if p.Color() == White && p != NoPiece {
+		l -= 'a' - 'A'
+	}
+	return l
+}
+
+func pieceFromLetter(l byte) (Piece, bool) {
+	c := White
+	if l >= 'a' && l <= 'z' {
+		c = Black
+		l -= 'a' - 'A'
+	}
+	switch l {
+	case 'P':
+		return NewPiece(c, Pawn), true
+	case 'N':
+		return NewPiece(c, Knight), true
+	case 'B':
+		return NewPiece(c, Bishop), true
+	case 'R':
+		return NewPiece(c, Rook), true
+	case 'Q':
+		return NewPiece(c, Queen), true
+	case 'K':
+		return NewPiece(c, King), true
+	}
+	return NoPiece, false
+}
+
+// Square indexes the board from a1 = 0 to h8 = 63.
+type Square int8
+
+const NoSquare Square = -1
+
+func NewSquare(file, rank int) Square {
+	return Square(rank*8 + file)
+}
+
+func (s Square) File() int {
+	return int(s) % 8
+}
+
+func (s Square) Rank() int {
+	return int(s) / 8
+}
+
+func (s Square) String() string {
+	if s == NoSquare {
+		return "-"
+	}
+	return string([]byte{byte('a' + s.File()), byte('1' + s.Rank())})
+}
+
+func ParseSquare(s string) (Square, error) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return NoSquare, fmt.Errorf("invalid square %q", s)
+	}
+	return NewSquare(int(s[0]-'a'), int(s[1]-'1')), nil
+}
+
+type CastlingRights uint8
+
+const (
+	WhiteKingSide CastlingRights = 1 << iota
+	WhiteQueenSide
+	BlackKingSide
+	BlackQueenSide
+)
+
+func (cr CastlingRights) String() string {
+	if cr == 0 {
+		return "-"
+	}
+	s := ""
+	if cr&WhiteKingSide != 0 {
+		s += "K"
+	}
+	if cr&WhiteQueenSide != 0 {
+		s += "Q"
+	}
+	if cr&BlackKingSide != 0 {
+		s += "k"
+	}
+	if cr&BlackQueenSide != 0 {
+		s += "q"
+	}
+	return s
+}
@@ -3,7 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +14,19 @@ type Config struct {
 	DatabaseURL string
 	JWTSecret   string
 	Port        string
+
+	// How long a disconnected player has to come back before the game is
+	// aborted or awarded to their opponent.
+	ReconnectGrace time.Duration
+
+	// Path of the UCI engine binary bots run, and how many copies of it
+	// may run at once.
+	StockfishPath  string
+	EnginePoolSize int
+
+	// Address of the internal listener serving runtime metrics at
+	// /debug/vars. Empty turns it off; keep it off the public network.
+	DebugAddr string
 }
 
 func getEnv(key, fallback string) string {
@@ -31,10 +46,26 @@ func LoadConfig() (*Config, error) {
 		port = ":" + port
 	}
 
+	graceSeconds, err := strconv.Atoi(getEnv("RECONNECT_GRACE_SECONDS", "60"))
+	if err != nil || graceSeconds <= 0 {
+		log.Printf("Invalid RECONNECT_GRACE_SECONDS, using 60")
+		graceSeconds = 60
+	}
+
+	poolSize, err := strconv.Atoi(getEnv("ENGINE_POOL_SIZE", "2"))
+	if err != nil || poolSize <= 0 {
+		log.Printf("Invalid ENGINE_POOL_SIZE, using 2")
+		poolSize = 2
+	}
+
 	return &Config{
-		DatabaseURL: getEnv("DATABASE_URL", ""),
-		JWTSecret:   getEnv("JWT_SECRET", ""),
-		Port:        port,
+		DatabaseURL:    getEnv("DATABASE_URL", ""),
+		JWTSecret:      getEnv("JWT_SECRET", ""),
+		Port:           port,
+		ReconnectGrace: time.Duration(graceSeconds) * time.Second,
+		StockfishPath:  getEnv("STOCKFISH_PATH", "stockfish"),
+		EnginePoolSize: poolSize,
+		DebugAddr:      getEnv("DEBUG_ADDR", "localhost:6060"),
 	}, nil
 }
 
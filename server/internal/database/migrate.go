@@ -7,7 +7,7 @@ import (
 )
 
 func Migrate() error {
-	err := DB.AutoMigrate(&models.User{}, &models.AIGame{}, &models.EngineAnalysis{}, &models.Game{}, &models.MatchmakingQueue{}, &models.Move{}, &models.Rating{}, &models.Spectator{})
+	err := DB.AutoMigrate(&models.User{}, &models.AIGame{}, &models.Challenge{}, &models.EngineAnalysis{}, &models.GameAnalysis{}, &models.Game{}, &models.MatchmakingQueue{}, &models.Move{}, &models.Rating{}, &models.RatingHistory{}, &models.Spectator{})
 	if err != nil {
 		log.Fatal("❌ Migration failed:", err)
 	}
@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"net/http"
+
+	"github.com/datmedevil17/chesss/internal/services/ai"
+	"github.com/datmedevil17/chesss/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *ai.Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: ai.NewService(),
+	}
+}
+
+func (h *Handler) CreateGame(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req CreateGameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	aiGame, err := h.service.CreateGame(userID, req.Difficulty, req.Color, req.TimeControl)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Game created", GameResponse{
+		ID:         aiGame.ID,
+		GameID:     aiGame.GameID,
+		Color:      aiGame.Color,
+		Difficulty: aiGame.Difficulty,
+	})
+}
@@ -0,0 +1,14 @@
+package ai
+
+type CreateGameRequest struct {
+	Difficulty  int    `json:"difficulty" binding:"required,min=1,max=8"`
+	Color       string `json:"color"`        // white | black | random (default)
+	TimeControl string `json:"time_control"` // 10+0 by default
+}
+
+type GameResponse struct {
+	ID         string `json:"id"`
+	GameID     string `json:"game_id"` // Connect to /api/v1/game/ws/:game_id to play
+	Color      string `json:"color"`   // The user's side
+	Difficulty int    `json:"difficulty"`
+}
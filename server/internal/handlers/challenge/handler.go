@@ -0,0 +1,121 @@
+package challenge
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datmedevil17/chesss/internal/models"
+	"github.com/datmedevil17/chesss/internal/services/challenge"
+	"github.com/datmedevil17/chesss/internal/services/events"
+	"github.com/datmedevil17/chesss/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *challenge.Service
+}
+
+func NewHandler(dispatcher *events.Dispatcher) *Handler {
+	return &Handler{
+		service: challenge.NewService(dispatcher),
+	}
+}
+
+func (h *Handler) Create(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req CreateChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ch, err := h.service.Create(userID, req.Username, req.TimeControl, req.Color, req.Rated)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Challenge sent", toResponse(ch))
+}
+
+// List returns the user's pending challenges, sent and received.
+func (h *Handler) List(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	challenges, err := h.service.List(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load challenges")
+		return
+	}
+
+	resp := make([]ChallengeResponse, len(challenges))
+	for i := range challenges {
+		resp[i] = toResponse(&challenges[i])
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Challenges fetched", resp)
+}
+
+func (h *Handler) Accept(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	ch, _, err := h.service.Accept(userID, c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Challenge accepted", toResponse(ch))
+}
+
+func (h *Handler) Decline(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	ch, err := h.service.Decline(userID, c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Challenge declined", toResponse(ch))
+}
+
+func (h *Handler) Cancel(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	ch, err := h.service.Cancel(userID, c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Challenge cancelled", toResponse(ch))
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, challenge.ErrNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, challenge.ErrNotPending):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, challenge.ErrExpired):
+		utils.ErrorResponse(c, http.StatusGone, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update challenge")
+	}
+}
+
+func toResponse(ch *models.Challenge) ChallengeResponse {
+	return ChallengeResponse{
+		ID:          ch.ID,
+		Challenger:  ch.Challenger.Username,
+		Challenged:  ch.Challenged.Username,
+		TimeControl: ch.TimeControl,
+		Mode:        ch.Mode,
+		Color:       ch.Color,
+		Rated:       ch.Rated,
+		Status:      ch.Status,
+		GameID:      ch.GameID,
+		ExpiresAt:   ch.ExpiresAt.UnixMilli(),
+	}
+}
@@ -0,0 +1,21 @@
+package challenge
+
+type CreateChallengeRequest struct {
+	Username    string `json:"username" binding:"required"`
+	TimeControl string `json:"time_control" binding:"required"` // 5+0, 3+2
+	Color       string `json:"color"`                           // white | black | random (default); the challenger's side
+	Rated       bool   `json:"rated"`
+}
+
+type ChallengeResponse struct {
+	ID          string `json:"id"`
+	Challenger  string `json:"challenger"`
+	Challenged  string `json:"challenged"`
+	TimeControl string `json:"time_control"`
+	Mode        string `json:"mode"`
+	Color       string `json:"color"`
+	Rated       bool   `json:"rated"`
+	Status      string `json:"status"`
+	GameID      string `json:"game_id,omitempty"` // Connect to /api/v1/game/ws/:game_id once accepted
+	ExpiresAt   int64  `json:"expires_at"`        // Unix timestamp (ms)
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/config"
+	"github.com/datmedevil17/chesss/internal/services/events"
+	"github.com/datmedevil17/chesss/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// How often an idle stream gets a comment line, so proxies keep it open.
+const keepAlivePeriod = 25 * time.Second
+
+type Handler struct {
+	dispatcher *events.Dispatcher
+	jwtSecret  string
+}
+
+func NewHandler(cfg *config.Config, dispatcher *events.Dispatcher) *Handler {
+	return &Handler{
+		dispatcher: dispatcher,
+		jwtSecret:  cfg.JWTSecret,
+	}
+}
+
+// Stream sends the user's events as Server-Sent Events until they
+// disconnect. Browsers' EventSource can't set headers, so the JWT may come
+// as ?token= instead of the Authorization header.
+func (h *Handler) Stream(c *gin.Context) {
+	tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if tokenString == "" {
+		tokenString = c.Query("token")
+	}
+	if tokenString == "" {
+		utils.ErrorResponse(c, http.StatusForbidden, "No token found")
+		return
+	}
+	claims, err := utils.ValidateToken(tokenString, h.jwtSecret)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Token expired or invalid. Please login again")
+		return
+	}
+
+	sub := h.dispatcher.Subscribe(claims.UserID)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(keepAlivePeriod)
+	defer keepAlive.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(event.Type), event)
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+		case <-c.Request.Context().Done():
+			return false
+		}
+		return true
+	})
+}
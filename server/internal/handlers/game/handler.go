@@ -1,32 +1,388 @@
 package game
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/datmedevil17/chesss/internal/database"
+	"github.com/datmedevil17/chesss/internal/config"
 	"github.com/datmedevil17/chesss/internal/models"
+	"github.com/datmedevil17/chesss/internal/services/analysis"
+	"github.com/datmedevil17/chesss/internal/services/engine"
+	"github.com/datmedevil17/chesss/internal/services/events"
 	"github.com/datmedevil17/chesss/internal/services/game"
+	"github.com/datmedevil17/chesss/internal/services/user"
 	"github.com/datmedevil17/chesss/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
 )
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
+// Page sizes for the live games directory.
+const (
+	defaultLiveLimit = 20
+	maxLiveLimit     = 100
+)
+
 type Handler struct {
 	hub       *game.Hub
+	service   *game.Service
+	analysis  *analysis.Service
+	users     *user.Service
 	jwtSecret string
 }
 
-func NewHandler(jwtSecret string) *Handler {
+func NewHandler(cfg *config.Config, engines *engine.Pool, analyzer *analysis.Service, dispatcher *events.Dispatcher) *Handler {
 	return &Handler{
-		hub:       game.NewHub(),
-		jwtSecret: jwtSecret,
+		hub:       game.NewHub(cfg.ReconnectGrace, engines, analyzer, dispatcher),
+		service:   game.NewService(),
+		analysis:  analyzer,
+		users:     user.NewService(),
+		jwtSecret: cfg.JWTSecret,
+	}
+}
+
+func (h *Handler) ExportPGN(c *gin.Context) {
+	gameID := c.Param("id")
+
+	pgn, err := h.service.ExportPGN(gameID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Game not found")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to export game")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pgn"`, gameID))
+	c.Data(http.StatusOK, "application/x-chess-pgn", []byte(pgn))
+}
+
+// maxImportBytes bounds the size of a PGN import request body.
+const maxImportBytes = 5 << 20
+
+func (h *Handler) ImportPGN(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	var req ImportPGNRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	games, err := h.service.ImportPGN(userID, req.PGN)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := ImportPGNResponse{Games: make([]ImportedGame, len(games))}
+	for i, g := range games {
+		resp.Games[i] = ImportedGame{
+			ID:    g.ID,
+			White: g.WhiteName,
+			Black: g.BlackName,
+			Moves: len(g.Moves),
+		}
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Games imported", resp)
+}
+
+// GetGame returns a game with its players, result and full move list.
+func (h *Handler) GetGame(c *gin.Context) {
+	g, err := h.service.GetGame(c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Game not found")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load game")
+		return
+	}
+
+	resp := GameDetailResponse{
+		GameSummary: toSummary(g, len(g.Moves)),
+		InitialFEN:  g.InitialFEN,
+		FEN:         g.FEN,
+		Moves:       make([]MoveResponse, len(g.Moves)),
+	}
+	for i, m := range g.Moves {
+		resp.Moves[i] = MoveResponse{
+			Number:  m.MoveNumber,
+			UCI:     m.FromSquare + m.ToSquare + m.Promotion,
+			SAN:     m.SAN,
+			FEN:     m.FEN,
+			ClockMs: m.ClockMs,
+		}
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Game fetched", resp)
+}
+
+// LiveGames lists the games being played right now. Query parameters: sort
+// (rating, the default, or viewers), mode and limit.
+func (h *Handler) LiveGames(c *gin.Context) {
+	sortBy := c.DefaultQuery("sort", game.SortByRating)
+	if sortBy != game.SortByRating && sortBy != game.SortByViewers {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("invalid sort %q", sortBy))
+		return
+	}
+	limit := defaultLiveLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", v))
+			return
+		}
+		limit = min(n, maxLiveLimit)
+	}
+	mode := c.Query("mode")
+
+	resp := []LiveGameResponse{}
+	for _, g := range h.hub.LiveGames(sortBy) {
+		if mode != "" && g.Mode != mode {
+			continue
+		}
+		resp = append(resp, LiveGameResponse{
+			ID:            g.GameID,
+			White:         LivePlayer{Username: g.WhiteName, Rating: g.WhiteRating},
+			Black:         LivePlayer{Username: g.BlackName, Rating: g.BlackRating},
+			AverageRating: g.AverageRating(),
+			Mode:          g.Mode,
+			TimeControl:   g.TimeControl,
+			Rated:         g.Rated,
+			FEN:           g.FEN,
+			MoveCount:     g.Plies,
+			Spectators:    g.Spectators,
+		})
+		if len(resp) == limit {
+			break
+		}
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Live games fetched", resp)
+}
+
+// ListUserGames returns a page of the games a user played, newest first.
+// Query parameters: mode, result (win|loss|draw), color (white|black),
+// opponent (username), rated (true|false), from and to (YYYY-MM-DD or
+// RFC 3339), cursor and limit.
+func (h *Handler) ListUserGames(c *gin.Context) {
+	u, err := h.users.GetByUsername(c.Param("username"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+
+	filter, err := h.historyFilter(c)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Nobody has played an opponent who doesn't exist.
+		utils.SuccessResponse(c, http.StatusOK, "Games fetched", GameListResponse{Games: []GameSummary{}})
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.service.ListUserGames(u.ID, filter)
+	if errors.Is(err, game.ErrInvalidFilter) {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load games")
+		return
+	}
+
+	resp := GameListResponse{Games: make([]GameSummary, len(page.Games)), NextCursor: page.NextCursor}
+	for i := range page.Games {
+		resp.Games[i] = toSummary(&page.Games[i], page.MoveCounts[page.Games[i].ID])
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Games fetched", resp)
+}
+
+func (h *Handler) historyFilter(c *gin.Context) (game.HistoryFilter, error) {
+	f := game.HistoryFilter{
+		Mode:   c.Query("mode"),
+		Result: c.Query("result"),
+		Color:  c.Query("color"),
+		Cursor: c.Query("cursor"),
 	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return f, fmt.Errorf("invalid limit %q", v)
+		}
+		f.Limit = n
+	}
+	if v := c.Query("rated"); v != "" {
+		rated, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid rated %q", v)
+		}
+		f.Rated = &rated
+	}
+	if v := c.Query("from"); v != "" {
+		from, _, err := parseDate(v)
+		if err != nil {
+			return f, err
+		}
+		f.From = &from
+	}
+	if v := c.Query("to"); v != "" {
+		to, dateOnly, err := parseDate(v)
+		if err != nil {
+			return f, err
+		}
+		// A plain date includes the whole day.
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		f.To = &to
+	}
+	if v := c.Query("opponent"); v != "" {
+		opponent, err := h.users.GetByUsername(v)
+		if err != nil {
+			return f, err
+		}
+		f.OpponentID = opponent.ID
+	}
+	return f, nil
+}
+
+// parseDate accepts YYYY-MM-DD or RFC 3339 and reports which it got.
+func parseDate(v string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", v)
+	}
+	return t, false, nil
+}
+
+func toSummary(g *models.Game, moves int) GameSummary {
+	white := PlayerSummary{ID: g.WhiteID, Username: g.White.Username}
+	black := PlayerSummary{ID: g.BlackID, Username: g.Black.Username}
+	if g.Status == "imported" {
+		white.Username, black.Username = g.WhiteName, g.BlackName
+	}
+	return GameSummary{
+		ID:          g.ID,
+		White:       white,
+		Black:       black,
+		Status:      g.Status,
+		Result:      g.Result,
+		Reason:      g.Reason,
+		Mode:        g.Mode,
+		TimeControl: g.TimeControl,
+		Rated:       g.Rated,
+		MoveCount:   moves,
+		CreatedAt:   g.CreatedAt,
+		StartedAt:   g.StartedAt,
+		FinishedAt:  g.FinishedAt,
+	}
+}
+
+// RequestAnalysis queues computer analysis of a finished game.
+func (h *Handler) RequestAnalysis(c *gin.Context) {
+	gameID := c.Param("id")
+
+	job, err := h.analysis.Request(gameID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Game not found")
+		return
+	case errors.Is(err, analysis.ErrNotFinished), errors.Is(err, analysis.ErrNoMoves):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to queue analysis")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Analysis queued", AnalysisResponse{
+		GameID: job.GameID,
+		Status: job.Status,
+	})
+}
+
+// GetAnalysis returns a game's analysis: its status and, once done, the
+// per-player summary and every move.
+func (h *Handler) GetAnalysis(c *gin.Context) {
+	gameID := c.Param("id")
+
+	job, err := h.analysis.GetAnalysis(gameID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Game has not been analyzed")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load analysis")
+		return
+	}
+
+	resp := AnalysisResponse{
+		GameID: job.GameID,
+		Status: job.Status,
+		Error:  job.Error,
+	}
+	if job.Status != "done" {
+		utils.SuccessResponse(c, http.StatusOK, "Analysis "+job.Status, resp)
+		return
+	}
+
+	rows, err := h.analysis.GetMoves(gameID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load analysis")
+		return
+	}
+	resp.White = &PlayerAnalysis{Accuracy: job.WhiteAccuracy}
+	resp.Black = &PlayerAnalysis{Accuracy: job.BlackAccuracy}
+	resp.Moves = make([]MoveAnalysis, len(rows))
+	for i, row := range rows {
+		resp.Moves[i] = MoveAnalysis{
+			MoveNumber:     row.MoveNumber,
+			FEN:            row.FEN,
+			Played:         row.PlayedMove,
+			Best:           row.BestMove,
+			Evaluation:     row.Evaluation,
+			Mate:           row.Mate,
+			Depth:          row.Depth,
+			Classification: row.Classification,
+			Accuracy:       row.Accuracy,
+		}
+
+		// The FEN is the position the move was played from, so its side
+		// to move is the mover.
+		player := resp.White
+		if fields := strings.Fields(row.FEN); len(fields) > 1 && fields[1] == "b" {
+			player = resp.Black
+		}
+		switch row.Classification {
+		case "inaccuracy":
+			player.Inaccuracies++
+		case "mistake":
+			player.Mistakes++
+		case "blunder":
+			player.Blunders++
+		}
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Analysis done", resp)
 }
 
 func (h *Handler) WSHandler(c *gin.Context) {
@@ -44,24 +400,18 @@ func (h *Handler) WSHandler(c *gin.Context) {
 		}
 	}
 
-	// 2. Fetch Game to determine Role
-	var role = "spectator"
-	isBotGame := c.Query("bot") == "true"
-	var gameModel models.Game
-	if err := database.GetDB().Preload("White").Preload("Black").Where("id = ?", gameID).First(&gameModel).Error; err != nil {
-		log.Printf("Game not found: %v", err)
-		// For bot games without a DB entry, human plays as white
-		if isBotGame {
-			role = "white"
-		}
-		// Otherwise proceed as spectator for non-bot games
-	} else {
-		if userID == gameModel.WhiteID {
-			role = "white"
-		} else if userID == gameModel.BlackID {
-			role = "black"
-		}
+	// 2. Load the game's room and determine Role
+	room, err := h.hub.GetRoom(gameID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Game not found")
+		return
 	}
+	if err != nil {
+		log.Printf("Failed to load game %s: %v", gameID, err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load game")
+		return
+	}
+	role := room.RoleFor(userID)
 
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -77,74 +427,34 @@ func (h *Handler) WSHandler(c *gin.Context) {
 
 	log.Printf("New Client Connected: UserID=%d, Role=%s, GameID=%s", userID, role, gameID)
 
-	room := h.hub.GetRoom(gameID)
-	room.Register <- client
-
-	// Check if user wants to play against AI (for testing/demo)
-	if isBotGame {
-		// Attempt to start a bot. Assuming "stockfish" is in PATH.
-		// In production, this logic belongs in Matchmaking/Hub startup.
-		game.NewBot(room, "stockfish")
-	}
-
-	// Load move history from database
-	var moves []models.Move
-	database.GetDB().Where("game_id = ?", gameID).Order("move_number ASC").Find(&moves)
-	history := make([]string, len(moves))
-	for i, m := range moves {
-		history[i] = m.FromSquare + m.ToSquare + m.Promotion
-	}
-
-	// Initialize room's move history and turn from DB state
-	room.MoveHistory = history
-	if len(history)%2 == 0 {
-		room.CurrentTurn = "white"
-	} else {
-		room.CurrentTurn = "black"
-	}
-
-	// Initialize room times from DB
-	whiteTime := gameModel.WhiteTimeRemaining
-	blackTime := gameModel.BlackTimeRemaining
-	if whiteTime == 0 {
-		whiteTime = 600 // Default 10 minutes if not set
-	}
-	if blackTime == 0 {
-		blackTime = 600
-	}
-	room.WhiteTime = whiteTime
-	room.BlackTime = blackTime
-
-	// Calculate LastMoveAt - use room.LastMoveTime if available, otherwise use now
-	lastMoveAt := room.LastMoveTime.UnixMilli()
-
-	// Send Init JSON
-	fen := gameModel.FEN
-	if fen == "" || fen == "startpos" {
-		fen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
-	}
-	initMsg := game.WSMessage{
-		Type: game.MsgInit,
-		Payload: game.InitPayload{
-			FEN:         fen,
-			History:     history,
-			WhiteID:     gameModel.WhiteID,
-			BlackID:     gameModel.BlackID,
-			WhiteName:   gameModel.White.Username,
-			BlackName:   gameModel.Black.Username,
-			Status:      gameModel.Status,
-			Color:       role,
-			WhiteTime:   whiteTime,
-			BlackTime:   blackTime,
-			LastMoveAt:  lastMoveAt,
-			CurrentTurn: room.CurrentTurn,
-		},
-	}
-	initBytes, _ := json.Marshal(initMsg)
-	client.Send <- initBytes
+	// Send Init JSON and start receiving broadcasts
+	room, err = h.hub.JoinRoom(gameID, client)
+	if err != nil {
+		log.Printf("Failed to join game %s: %v", gameID, err)
+		conn.Close()
+		return
+	}
 
 	// Allow collection of memory referenced by the caller by doing all work in
 	// new goroutines.
 	go client.WritePump()
 	go client.ReadPump(room)
 }
+
+// TVHandler streams the featured live game over a WebSocket, moving on to
+// the next one whenever it ends. A token is optional.
+func (h *Handler) TVHandler(c *gin.Context) {
+	var userID uint
+	if tokenString := c.Query("token"); tokenString != "" {
+		if claims, err := utils.ValidateToken(tokenString, h.jwtSecret); err == nil {
+			userID = claims.UserID
+		}
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	log.Printf("New TV viewer connected: UserID=%d", userID)
+	go h.hub.ServeTV(conn, userID)
+}
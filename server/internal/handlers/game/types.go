@@ -0,0 +1,105 @@
+package game
+
+import "time"
+
+type ImportPGNRequest struct {
+	PGN string `json:"pgn" binding:"required"`
+}
+
+type ImportedGame struct {
+	ID    string `json:"id"`
+	White string `json:"white"`
+	Black string `json:"black"`
+	Moves int    `json:"moves"`
+}
+
+type ImportPGNResponse struct {
+	Games []ImportedGame `json:"games"`
+}
+
+type AnalysisResponse struct {
+	GameID string          `json:"game_id"`
+	Status string          `json:"status"` // pending | running | done | failed
+	Error  string          `json:"error,omitempty"`
+	White  *PlayerAnalysis `json:"white,omitempty"`
+	Black  *PlayerAnalysis `json:"black,omitempty"`
+	Moves  []MoveAnalysis  `json:"moves,omitempty"`
+}
+
+type PlayerAnalysis struct {
+	Accuracy     float64 `json:"accuracy"` // 0-100
+	Inaccuracies int     `json:"inaccuracies"`
+	Mistakes     int     `json:"mistakes"`
+	Blunders     int     `json:"blunders"`
+}
+
+type MoveAnalysis struct {
+	MoveNumber     int     `json:"move_number"`
+	FEN            string  `json:"fen"`        // Position before the move
+	Played         string  `json:"played"`     // UCI
+	Best           string  `json:"best"`       // Engine's choice in that position, UCI
+	Evaluation     int     `json:"evaluation"` // After the move, centipawns for white
+	Mate           int     `json:"mate,omitempty"`
+	Depth          int     `json:"depth"`
+	Classification string  `json:"classification,omitempty"`
+	Accuracy       float64 `json:"accuracy"`
+}
+
+type PlayerSummary struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+type GameSummary struct {
+	ID          string        `json:"id"`
+	White       PlayerSummary `json:"white"`
+	Black       PlayerSummary `json:"black"`
+	Status      string        `json:"status"`
+	Result      string        `json:"result"`
+	Reason      string        `json:"reason"`
+	Mode        string        `json:"mode"`
+	TimeControl string        `json:"time_control"`
+	Rated       bool          `json:"rated"`
+	MoveCount   int           `json:"move_count"`
+	CreatedAt   time.Time     `json:"created_at"`
+	StartedAt   *time.Time    `json:"started_at"`
+	FinishedAt  *time.Time    `json:"finished_at"`
+}
+
+type GameListResponse struct {
+	Games      []GameSummary `json:"games"`
+	NextCursor string        `json:"next_cursor,omitempty"` // Pass as ?cursor= for the next page
+}
+
+type MoveResponse struct {
+	Number  int    `json:"number"` // Ply, starting at 1
+	UCI     string `json:"uci"`
+	SAN     string `json:"san"`
+	FEN     string `json:"fen"`      // Position after the move
+	ClockMs int64  `json:"clock_ms"` // Mover's remaining time after the move
+}
+
+type GameDetailResponse struct {
+	GameSummary
+	InitialFEN string         `json:"initial_fen"` // Empty means the standard starting position
+	FEN        string         `json:"fen"`
+	Moves      []MoveResponse `json:"moves"`
+}
+
+type LivePlayer struct {
+	Username string `json:"username"`
+	Rating   int    `json:"rating"` // 0 if unrated in this mode
+}
+
+type LiveGameResponse struct {
+	ID            string     `json:"id"` // Watch at /api/v1/game/ws/:id
+	White         LivePlayer `json:"white"`
+	Black         LivePlayer `json:"black"`
+	AverageRating int        `json:"average_rating"`
+	Mode          string     `json:"mode"`
+	TimeControl   string     `json:"time_control"`
+	Rated         bool       `json:"rated"`
+	FEN           string     `json:"fen"`
+	MoveCount     int        `json:"move_count"`
+	Spectators    int        `json:"spectators"`
+}
@@ -0,0 +1,91 @@
+package leaderboard
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/datmedevil17/chesss/internal/config"
+	"github.com/datmedevil17/chesss/internal/services/leaderboard"
+	"github.com/datmedevil17/chesss/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service   *leaderboard.Service
+	jwtSecret string
+}
+
+func NewHandler(cfg *config.Config) *Handler {
+	return &Handler{
+		service:   leaderboard.NewService(),
+		jwtSecret: cfg.JWTSecret,
+	}
+}
+
+// Get lists the top players of a mode. The route is public; a valid token
+// also adds the requester's own rank.
+func (h *Handler) Get(c *gin.Context) {
+	limit := leaderboard.DefaultLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > leaderboard.MaxLimit {
+			utils.ErrorResponse(c, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(leaderboard.MaxLimit))
+			return
+		}
+		limit = n
+	}
+
+	board, top, err := h.service.Top(c.Param("mode"), limit)
+	if errors.Is(err, leaderboard.ErrUnknownMode) {
+		utils.ErrorResponse(c, http.StatusNotFound, "No leaderboard for mode "+c.Param("mode"))
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load leaderboard")
+		return
+	}
+
+	resp := LeaderboardResponse{
+		Mode:      board.Mode,
+		UpdatedAt: board.BuiltAt,
+		Players:   make([]EntryResponse, len(top)),
+	}
+	for i, e := range top {
+		resp.Players[i] = toEntry(e)
+	}
+	if userID, ok := h.requester(c); ok {
+		if e, ok := board.Rank(userID); ok {
+			you := toEntry(e)
+			resp.You = &you
+		}
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Leaderboard fetched", resp)
+}
+
+// requester returns the caller's user ID when they sent a valid token.
+func (h *Handler) requester(c *gin.Context) (uint, bool) {
+	tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if tokenString == "" {
+		return 0, false
+	}
+	claims, err := utils.ValidateToken(tokenString, h.jwtSecret)
+	if err != nil {
+		return 0, false
+	}
+	return claims.UserID, true
+}
+
+func toEntry(e leaderboard.Entry) EntryResponse {
+	return EntryResponse{
+		Rank:      e.Rank,
+		Username:  e.Username,
+		Rating:    e.Rating,
+		Deviation: int(e.Deviation),
+		Games:     e.GamesPlayed,
+		Wins:      e.Wins,
+		Losses:    e.Losses,
+		Draws:     e.Draws,
+	}
+}
@@ -0,0 +1,21 @@
+package leaderboard
+
+import "time"
+
+type EntryResponse struct {
+	Rank      int    `json:"rank"`
+	Username  string `json:"username"`
+	Rating    int    `json:"rating"`
+	Deviation int    `json:"deviation"`
+	Games     int    `json:"games"`
+	Wins      int    `json:"wins"`
+	Losses    int    `json:"losses"`
+	Draws     int    `json:"draws"`
+}
+
+type LeaderboardResponse struct {
+	Mode      string          `json:"mode"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Players   []EntryResponse `json:"players"`
+	You       *EntryResponse  `json:"you"` // The requester's own entry; null if anonymous or unranked
+}
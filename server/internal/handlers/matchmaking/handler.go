@@ -12,9 +12,9 @@ type Handler struct {
 	service *matchmaking.Service
 }
 
-func NewHandler() *Handler {
+func NewHandler(notifier matchmaking.Notifier) *Handler {
 	return &Handler{
-		service: matchmaking.NewService(),
+		service: matchmaking.NewService(notifier),
 	}
 }
 
@@ -31,7 +31,6 @@ func (h *Handler) Join(c *gin.Context) {
 		userID,
 		req.Mode,
 		req.TimeControl,
-		req.Rating,
 	); err != nil {
 		utils.ErrorResponse(c, 400, err.Error())
 		return
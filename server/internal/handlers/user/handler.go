@@ -1,12 +1,17 @@
 package user
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/datmedevil17/chesss/internal/models"
+	"github.com/datmedevil17/chesss/internal/services/ai"
+	"github.com/datmedevil17/chesss/internal/services/rating"
 	"github.com/datmedevil17/chesss/internal/services/user"
 	"github.com/datmedevil17/chesss/internal/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type Handler struct {
@@ -35,6 +40,71 @@ func (h *Handler) Me(c *gin.Context) {
 	})
 }
 
+// Profile returns a user's public profile, looked up by username.
+func (h *Handler) Profile(c *gin.Context) {
+	p, err := h.service.GetProfile(c.Param("username"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load profile")
+		return
+	}
+
+	resp := ProfileResponse{
+		Username:    p.User.Username,
+		JoinedAt:    p.User.CreatedAt,
+		IsBot:       p.User.IsBot,
+		Ratings:     make([]ModeRating, len(p.Ratings)),
+		Overall:     RecordResponse(p.Overall),
+		AsWhite:     RecordResponse(p.AsWhite),
+		AsBlack:     RecordResponse(p.AsBlack),
+		Streaks:     StreaksResponse(p.Streaks),
+		RecentGames: make([]RecentGame, len(p.RecentGames)),
+	}
+	for i, r := range p.Ratings {
+		resp.Ratings[i] = ModeRating{
+			Mode:        r.Mode,
+			Rating:      r.Value,
+			Deviation:   int(r.Deviation),
+			Provisional: r.Deviation > rating.ProvisionalDeviation,
+			Games:       r.GamesPlayed,
+			Wins:        r.Wins,
+			Losses:      r.Losses,
+			Draws:       r.Draws,
+		}
+	}
+	for i, g := range p.RecentGames {
+		recent := RecentGame{
+			ID:          g.ID,
+			Opponent:    g.Black.Username,
+			Color:       "white",
+			Reason:      g.Reason,
+			Status:      g.Status,
+			Mode:        g.Mode,
+			TimeControl: g.TimeControl,
+			Rated:       g.Rated,
+			CreatedAt:   g.CreatedAt,
+		}
+		won, lost := "1-0", "0-1"
+		if g.WhiteID != p.User.ID {
+			recent.Opponent, recent.Color = g.White.Username, "black"
+			won, lost = lost, won
+		}
+		switch g.Result {
+		case won:
+			recent.Result = "win"
+		case lost:
+			recent.Result = "loss"
+		case "1/2-1/2":
+			recent.Result = "draw"
+		}
+		resp.RecentGames[i] = recent
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Profile fetched", resp)
+}
+
 func (h *Handler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -42,6 +112,12 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
+	// The engine's account is created on demand under this name.
+	if strings.EqualFold(req.Username, ai.BotUsername) {
+		utils.ErrorResponse(c, http.StatusConflict, "Username is reserved")
+		return
+	}
+
 	// Check if user exists
 	if _, err := h.service.GetByEmail(req.Email); err == nil {
 		utils.ErrorResponse(c, http.StatusConflict, "User already exists")
@@ -1,5 +1,7 @@
 package user
 
+import "time"
+
 type UserResponse struct {
 	ID       uint   `json:"id"`
 	Email    string `json:"email"`
@@ -21,3 +23,53 @@ type LoginResponse struct {
 	Token string       `json:"token"`
 	User  UserResponse `json:"user"`
 }
+
+// ProfileResponse is a user's public profile. It has no email on purpose.
+type ProfileResponse struct {
+	Username    string          `json:"username"`
+	JoinedAt    time.Time       `json:"joined_at"`
+	IsBot       bool            `json:"is_bot"`
+	Ratings     []ModeRating    `json:"ratings"`
+	Overall     RecordResponse  `json:"overall"`
+	AsWhite     RecordResponse  `json:"as_white"`
+	AsBlack     RecordResponse  `json:"as_black"`
+	Streaks     StreaksResponse `json:"streaks"`
+	RecentGames []RecentGame    `json:"recent_games"`
+}
+
+type ModeRating struct {
+	Mode        string `json:"mode"`
+	Rating      int    `json:"rating"`
+	Deviation   int    `json:"deviation"`
+	Provisional bool   `json:"provisional"`
+	Games       int    `json:"games"`
+	Wins        int    `json:"wins"`
+	Losses      int    `json:"losses"`
+	Draws       int    `json:"draws"`
+}
+
+type RecordResponse struct {
+	Games  int `json:"games"`
+	Wins   int `json:"wins"`
+	Losses int `json:"losses"`
+	Draws  int `json:"draws"`
+}
+
+type StreaksResponse struct {
+	LongestWin  int `json:"longest_win"`
+	LongestLoss int `json:"longest_loss"`
+	Current     int `json:"current"` // Positive for a winning run, negative for a losing one
+}
+
+type RecentGame struct {
+	ID          string    `json:"id"`
+	Opponent    string    `json:"opponent"`
+	Color       string    `json:"color"`  // The profile owner's side
+	Result      string    `json:"result"` // win | loss | draw, empty while in progress
+	Reason      string    `json:"reason"`
+	Status      string    `json:"status"`
+	Mode        string    `json:"mode"`
+	TimeControl string    `json:"time_control"`
+	Rated       bool      `json:"rated"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -7,8 +7,13 @@ type AIGame struct {
 
 	UserID uint `gorm:"index"`
 
+	GameID string `gorm:"uniqueIndex"` // The Game the moves are stored under
+
 	Difficulty int
-	// 1–8 (Stockfish depth)
+	// 1–8, see engine.LevelStrength
+
+	Color string
+	// white | black (the user's side)
 
 	FEN string `gorm:"type:text"`
 
@@ -16,7 +21,7 @@ type AIGame struct {
 	// active | finished
 
 	Result string
-	// win | loss | draw
+	// win | loss | draw | aborted
 
 	CreatedAt time.Time
 	FinishedAt *time.Time
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Challenge is one user inviting another to a game.
+type Challenge struct {
+	ID string `gorm:"primaryKey"` // UUID
+
+	ChallengerID uint `gorm:"index"`
+	ChallengedID uint `gorm:"index"`
+
+	Challenger User `gorm:"foreignKey:ChallengerID"`
+	Challenged User `gorm:"foreignKey:ChallengedID"`
+
+	TimeControl string
+	Mode        string
+	// bullet | blitz | rapid, from TimeControl
+
+	Color string
+	// white | black | random (the challenger's side)
+
+	Rated bool
+
+	Status string `gorm:"index"`
+	// pending | accepted | declined | cancelled | expired
+
+	GameID string // Set once accepted
+
+	ExpiresAt   time.Time
+	RespondedAt *time.Time
+
+	CreatedAt time.Time
+}
@@ -2,12 +2,17 @@ package models
 
 import "time"
 
+// EngineAnalysis is the computer's verdict on one move of a game. FEN,
+// Depth and BestMove describe the position the move was played from;
+// Evaluation and Mate the position it led to.
 type EngineAnalysis struct {
 	ID uint `gorm:"primaryKey"`
 
-	GameID string `gorm:"index"`
+	GameID string `gorm:"index;uniqueIndex:idx_engine_analysis_game_move"`
 	MoveID *uint  `gorm:"index"`
 
+	MoveNumber int `gorm:"uniqueIndex:idx_engine_analysis_game_move"` // Ply, as in Move.MoveNumber
+
 	FEN string `gorm:"type:text"`
 
 	Depth int
@@ -15,7 +20,33 @@ type EngineAnalysis struct {
 	Evaluation int
 	// centipawns (positive = white advantage)
 
-	BestMove string `gorm:"size:10"`
+	Mate int // Moves to mate, signed like Evaluation; 0 when there is none
+
+	BestMove   string `gorm:"size:10"`
+	PlayedMove string `gorm:"size:10"`
+
+	Classification string `gorm:"size:20"`
+	// inaccuracy | mistake | blunder, empty for other moves
+
+	Accuracy float64 // 0-100, how much of the mover's winning chances the move kept
 
 	CreatedAt time.Time
 }
+
+// GameAnalysis tracks the analysis job for a game and its per-player
+// summary.
+type GameAnalysis struct {
+	GameID string `gorm:"primaryKey"`
+
+	Status string `gorm:"index"`
+	// pending | running | done | failed
+
+	Error string
+
+	WhiteAccuracy float64
+	BlackAccuracy float64
+
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt *time.Time
+}
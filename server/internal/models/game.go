@@ -11,14 +11,20 @@ type Game struct {
 	White User `gorm:"foreignKey:WhiteID"`
 	Black User `gorm:"foreignKey:BlackID"`
 
+	// Player names from the PGN tags of imported games. Both seats of an
+	// imported game belong to the importing user.
+	WhiteName string
+	BlackName string
+
 	Status string `gorm:"index"`
-	// waiting | active | finished
+	// waiting | active | finished | aborted | imported
 
 	Result string
 	// 1-0 | 0-1 | 1/2-1/2 | *
 
 	Reason string
-	// checkmate | resign | timeout | draw
+	// checkmate | resign | timeout | stalemate | insufficient_material |
+	// threefold_repetition | fifty_move_rule | agreement | abandonment | aborted
 
 	Mode string
 	// bullet | blitz | rapid | ai
@@ -26,7 +32,11 @@ type Game struct {
 	TimeControl string
 	// 5+0, 3+2, etc.
 
-	FEN string `gorm:"type:text"`
+	Rated bool
+
+	FEN string `gorm:"type:text"` // Current position, updated after every move
+
+	InitialFEN string `gorm:"type:text"` // Starting position; empty means the standard one
 
 	Moves []Move `gorm:"foreignKey:GameID"`
 
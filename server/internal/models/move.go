@@ -20,5 +20,7 @@ type Move struct {
 
 	FEN string `gorm:"type:text"`
 
+	ClockMs int64 // Mover's remaining time after this move, increment included
+
 	CreatedAt time.Time
 }
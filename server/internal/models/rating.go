@@ -5,11 +5,15 @@ import "time"
 type Rating struct {
 	ID     uint `gorm:"primaryKey"`
 
-	UserID uint `gorm:"index;not null"`
+	UserID uint `gorm:"index;uniqueIndex:idx_rating_user_mode;not null"`
 	User   User `gorm:"foreignKey:UserID"`
 
-	Mode   string `gorm:"size:20;index"` // bullet | blitz | rapid
-	Value  int    `gorm:"default:1200"`
+	Mode   string `gorm:"size:20;index;uniqueIndex:idx_rating_user_mode;index:idx_rating_mode_value,priority:1"` // bullet | blitz | rapid
+	Value  int    `gorm:"default:1200;index:idx_rating_mode_value,priority:2"`
+
+	// Glicko-2 rating deviation and volatility
+	Deviation  float64 `gorm:"default:350"`
+	Volatility float64 `gorm:"default:0.06"`
 
 	GamesPlayed int `gorm:"default:0"`
 	Wins        int `gorm:"default:0"`
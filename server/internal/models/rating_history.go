@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RatingHistory records one player's rating change from one rated game.
+type RatingHistory struct {
+	ID uint `gorm:"primaryKey"`
+
+	UserID uint   `gorm:"index;uniqueIndex:idx_rating_history_game_user;not null"`
+	GameID string `gorm:"index;uniqueIndex:idx_rating_history_game_user;not null"`
+
+	Mode string `gorm:"size:20;index"`
+
+	RatingBefore int
+	RatingAfter  int
+
+	Deviation  float64
+	Volatility float64
+
+	CreatedAt time.Time
+}
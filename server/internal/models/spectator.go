@@ -9,4 +9,5 @@ type Spectator struct {
 	UserID uint  `gorm:"index"`
 
 	JoinedAt time.Time
+	LeftAt   *time.Time // Nil while they are still watching
 }
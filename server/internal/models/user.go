@@ -9,6 +9,7 @@ type User struct {
 	Password  string    `gorm:"not null"`
 
 	IsBanned  bool      `gorm:"default:false"`
+	IsBot     bool      `gorm:"default:false"` // Engine account seated in AI games; cannot log in
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/chess"
+	"github.com/datmedevil17/chesss/internal/database"
+	"github.com/datmedevil17/chesss/internal/models"
+	"github.com/datmedevil17/chesss/internal/services/engine"
+	"github.com/datmedevil17/chesss/internal/services/game"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BotUsername is the account every engine seat belongs to.
+const BotUsername = "stockfish"
+
+// DefaultTimeControl applies when a game against the engine is created
+// without one.
+const DefaultTimeControl = "10+0"
+
+type Service struct{}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+// CreateGame starts a game between userID and the engine. color is the
+// user's side: "white", "black", or "random"/"" for a coin toss. The bot
+// itself joins when the game's room is first opened.
+func (s *Service) CreateGame(userID uint, difficulty int, color, timeControl string) (*models.AIGame, error) {
+	if _, err := engine.LevelStrength(difficulty); err != nil {
+		return nil, err
+	}
+
+	switch color {
+	case "white", "black":
+	case "", "random":
+		color = "white"
+		if rand.Intn(2) == 1 {
+			color = "black"
+		}
+	default:
+		return nil, fmt.Errorf("invalid color %q", color)
+	}
+
+	if timeControl == "" {
+		timeControl = DefaultTimeControl
+	}
+	tc, err := game.ParseTimeControl(timeControl)
+	if err != nil {
+		return nil, err
+	}
+
+	bot, err := s.botUser()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	g := &models.Game{
+		ID:                 uuid.NewString(),
+		WhiteID:            userID,
+		BlackID:            bot.ID,
+		Status:             "active",
+		Mode:               "ai",
+		TimeControl:        timeControl,
+		FEN:                chess.StartFEN,
+		WhiteTimeRemaining: int(tc.Base.Seconds()),
+		BlackTimeRemaining: int(tc.Base.Seconds()),
+		StartedAt:          &now,
+	}
+	if color == "black" {
+		g.WhiteID, g.BlackID = bot.ID, userID
+	}
+
+	aiGame := &models.AIGame{
+		ID:         uuid.NewString(),
+		UserID:     userID,
+		GameID:     g.ID,
+		Difficulty: difficulty,
+		Color:      color,
+		FEN:        chess.StartFEN,
+		Status:     "active",
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("White", "Black").Create(g).Error; err != nil {
+			return err
+		}
+		return tx.Create(aiGame).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return aiGame, nil
+}
+
+// botUser returns the engine's account, creating it on first use. Its
+// password is not a bcrypt hash, so nobody can log in as it.
+func (s *Service) botUser() (*models.User, error) {
+	var bot models.User
+	err := database.GetDB().Where("is_bot = ?", true).Order("id ASC").First(&bot).Error
+	if err == nil {
+		return &bot, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Registration reserves BotUsername, but a player may have taken it
+	// before it was.
+	name := BotUsername
+	var taken int64
+	database.GetDB().Model(&models.User{}).Where("username = ?", name).Count(&taken)
+	if taken > 0 {
+		name = BotUsername + "-" + uuid.NewString()[:8]
+	}
+	bot = models.User{Username: name, Email: name + "@bots.local", Password: "!", IsBot: true}
+	if err := database.GetDB().Create(&bot).Error; err != nil {
+		// Another request may have created it first.
+		if database.GetDB().Where("is_bot = ?", true).Order("id ASC").First(&bot).Error == nil {
+			return &bot, nil
+		}
+		return nil, err
+	}
+	return &bot, nil
+}
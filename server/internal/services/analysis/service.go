@@ -0,0 +1,330 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/chess"
+	"github.com/datmedevil17/chesss/internal/database"
+	"github.com/datmedevil17/chesss/internal/models"
+	"github.com/datmedevil17/chesss/internal/services/engine"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Search limits for each analyzed position; whichever comes first.
+	searchDepth = 14
+	searchTime  = time.Second
+
+	// How long one position may wait for an engine and search.
+	positionTimeout = 30 * time.Second
+
+	// A job still pending or running after this long is assumed lost, e.g.
+	// to a restart, and may be requested again.
+	staleAfter = 15 * time.Minute
+
+	// Analyses run one at a time so bots keep most of the engine pool.
+	maxConcurrentJobs = 1
+
+	// Evaluations are capped here, and mates count as this much.
+	mateScore = 10000
+)
+
+// Losses in the mover's win percentage from which a move is flagged.
+const (
+	inaccuracyDrop = 5
+	mistakeDrop    = 10
+	blunderDrop    = 15
+)
+
+var (
+	ErrNotFinished = errors.New("only finished games can be analyzed")
+	ErrNoMoves     = errors.New("game has no moves to analyse")
+)
+
+// Service runs post-game computer analysis on the shared engine pool.
+type Service struct {
+	engines *engine.Pool
+	jobs    chan struct{} // one token per running analysis
+}
+
+func NewService(engines *engine.Pool) *Service {
+	return &Service{
+		engines: engines,
+		jobs:    make(chan struct{}, maxConcurrentJobs),
+	}
+}
+
+// Request queues an analysis of a finished or imported game and returns
+// its job. A game already analyzed, or being analyzed, is not queued again;
+// failed and stale jobs are retried.
+func (s *Service) Request(gameID string) (*models.GameAnalysis, error) {
+	var game models.Game
+	if err := database.GetDB().Where("id = ?", gameID).First(&game).Error; err != nil {
+		return nil, err
+	}
+	if game.Status != "finished" && game.Status != "imported" {
+		return nil, ErrNotFinished
+	}
+	var moves int64
+	database.GetDB().Model(&models.Move{}).Where("game_id = ?", gameID).Count(&moves)
+	if moves == 0 {
+		return nil, ErrNoMoves
+	}
+
+	// Whichever request creates or revives the row starts the job.
+	created := database.GetDB().Clauses(clause.OnConflict{DoNothing: true}).Create(&models.GameAnalysis{
+		GameID: gameID,
+		Status: "pending",
+	})
+	if created.Error != nil {
+		return nil, created.Error
+	}
+	start := created.RowsAffected == 1
+	if !start {
+		revived := database.GetDB().Model(&models.GameAnalysis{}).
+			Where("game_id = ? AND (status = ? OR (status IN ? AND updated_at < ?))",
+				gameID, "failed", []string{"pending", "running"}, time.Now().Add(-staleAfter)).
+			Updates(map[string]interface{}{"status": "pending", "error": ""})
+		if revived.Error != nil {
+			return nil, revived.Error
+		}
+		start = revived.RowsAffected == 1
+	}
+	if start {
+		go s.run(gameID)
+	}
+
+	return s.GetAnalysis(gameID)
+}
+
+// GetAnalysis returns the analysis job for a game.
+func (s *Service) GetAnalysis(gameID string) (*models.GameAnalysis, error) {
+	var a models.GameAnalysis
+	if err := database.GetDB().Where("game_id = ?", gameID).First(&a).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetMoves returns the per-move analysis of a game in move order.
+func (s *Service) GetMoves(gameID string) ([]models.EngineAnalysis, error) {
+	var rows []models.EngineAnalysis
+	err := database.GetDB().Where("game_id = ?", gameID).Order("move_number ASC").Find(&rows).Error
+	return rows, err
+}
+
+func (s *Service) run(gameID string) {
+	s.jobs <- struct{}{}
+	defer func() { <-s.jobs }()
+
+	setStatus(gameID, "running", "")
+	if err := s.analyse(gameID); err != nil {
+		log.Printf("Analysis of game %s failed: %v", gameID, err)
+		setStatus(gameID, "failed", err.Error())
+	}
+}
+
+func setStatus(gameID, status, errMsg string) {
+	database.GetDB().Model(&models.GameAnalysis{}).Where("game_id = ?", gameID).
+		Updates(map[string]interface{}{"status": status, "error": errMsg})
+}
+
+// eval is a position's evaluation from white's point of view.
+type eval struct {
+	cp       int // Capped at ±mateScore; mates score the cap
+	mate     int
+	bestMove string
+	depth    int
+}
+
+func (s *Service) analyse(gameID string) error {
+	var game models.Game
+	err := database.GetDB().
+		Preload("Moves", func(db *gorm.DB) *gorm.DB {
+			return db.Order("move_number ASC")
+		}).
+		Where("id = ?", gameID).
+		First(&game).Error
+	if err != nil {
+		return err
+	}
+
+	startFEN := game.InitialFEN
+	if startFEN == "" {
+		startFEN = chess.StartFEN
+	}
+	pos, err := chess.ParseFEN(startFEN)
+	if err != nil {
+		return err
+	}
+
+	// Evaluate every position, from the start to after the last move.
+	positions := []*chess.Position{pos}
+	for _, m := range game.Moves {
+		move, err := pos.ParseMove(m.FromSquare + m.ToSquare + m.Promotion)
+		if err != nil {
+			return fmt.Errorf("move %d: %w", m.MoveNumber, err)
+		}
+		pos = pos.Play(move)
+		positions = append(positions, pos)
+	}
+	evals := make([]eval, len(positions))
+	for i, p := range positions {
+		if evals[i], err = s.evaluate(gameID, p); err != nil {
+			return fmt.Errorf("position %d: %w", i, err)
+		}
+	}
+
+	rows := make([]models.EngineAnalysis, len(game.Moves))
+	var sums, counts [2]float64
+	for i, m := range game.Moves {
+		moveID := m.ID
+		mover := positions[i].Turn()
+		before := winPercent(evals[i].cp, mover)
+		after := winPercent(evals[i+1].cp, mover)
+		accuracy := moveAccuracy(before - after)
+
+		rows[i] = models.EngineAnalysis{
+			GameID:         gameID,
+			MoveID:         &moveID,
+			MoveNumber:     m.MoveNumber,
+			FEN:            positions[i].FEN(),
+			Depth:          evals[i].depth,
+			Evaluation:     evals[i+1].cp,
+			Mate:           evals[i+1].mate,
+			BestMove:       evals[i].bestMove,
+			PlayedMove:     m.FromSquare + m.ToSquare + m.Promotion,
+			Classification: classify(before - after),
+			Accuracy:       accuracy,
+		}
+		sums[mover] += accuracy
+		counts[mover]++
+	}
+
+	now := time.Now()
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("game_id = ?", gameID).Delete(&models.EngineAnalysis{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&rows).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.GameAnalysis{}).Where("game_id = ?", gameID).Updates(map[string]interface{}{
+			"status":         "done",
+			"error":          "",
+			"white_accuracy": average(sums[chess.White], counts[chess.White]),
+			"black_accuracy": average(sums[chess.Black], counts[chess.Black]),
+			"completed_at":   now,
+		}).Error
+	})
+}
+
+// evaluate searches one position at full strength.
+func (s *Service) evaluate(gameID string, pos *chess.Position) (eval, error) {
+	// Finished positions have no move to search.
+	if len(pos.LegalMoves()) == 0 {
+		if pos.InCheck() {
+			return whiteView(eval{cp: -mateScore}, pos.Turn()), nil
+		}
+		return eval{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), positionTimeout)
+	defer cancel()
+
+	var res engine.SearchResult
+	err := s.engines.Do(ctx, func(e *engine.Engine) error {
+		if err := e.SetStrength(engine.Strength{SkillLevel: 20}); err != nil {
+			return err
+		}
+		var err error
+		res, err = e.Analyze(ctx, engine.SearchParams{
+			FEN:      pos.FEN(),
+			Depth:    searchDepth,
+			MoveTime: searchTime,
+			MultiPV:  1,
+			GameID:   "analysis:" + gameID,
+		})
+		return err
+	})
+	if err != nil {
+		return eval{}, err
+	}
+
+	ev := eval{bestMove: res.BestMove}
+	if len(res.Lines) > 0 {
+		info := res.Lines[0]
+		ev.depth = info.Depth
+		switch {
+		case info.Score.IsMate() && info.Score.Mate > 0:
+			ev.cp, ev.mate = mateScore, info.Score.Mate
+		case info.Score.IsMate():
+			ev.cp, ev.mate = -mateScore, info.Score.Mate
+		default:
+			ev.cp = clamp(info.Score.CP, -mateScore, mateScore)
+		}
+	}
+	return whiteView(ev, pos.Turn()), nil
+}
+
+// whiteView turns an evaluation from the side to move's point of view
+// into white's.
+func whiteView(ev eval, turn chess.Color) eval {
+	if turn == chess.Black {
+		ev.cp, ev.mate = -ev.cp, -ev.mate
+	}
+	return ev
+}
+
+// winPercent maps a white-relative evaluation to c's chance of winning,
+// 0-100, using the curve fitted to online play.
+func winPercent(cp int, c chess.Color) float64 {
+	if c == chess.Black {
+		cp = -cp
+	}
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(cp)))-1)
+}
+
+// moveAccuracy scores a move by how much of the mover's win percentage it
+// gave away.
+func moveAccuracy(drop float64) float64 {
+	if drop < 0 {
+		drop = 0
+	}
+	return math.Max(0, math.Min(100, 103.1668*math.Exp(-0.04354*drop)-3.1669))
+}
+
+func classify(drop float64) string {
+	switch {
+	case drop >= blunderDrop:
+		return "blunder"
+	case drop >= mistakeDrop:
+		return "mistake"
+	case drop >= inaccuracyDrop:
+		return "inaccuracy"
+	}
+	return ""
+}
+
+func average(sum, n float64) float64 {
+	if n == 0 {
+		return 0
+	}
+	return math.Round(sum/n*10) / 10
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,247 @@
+package challenge
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/database"
+	"github.com/datmedevil17/chesss/internal/models"
+	"github.com/datmedevil17/chesss/internal/services/events"
+	"github.com/datmedevil17/chesss/internal/services/game"
+	"github.com/datmedevil17/chesss/internal/services/matchmaking"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// How long a challenge waits for an answer.
+const challengeTTL = 10 * time.Minute
+
+var (
+	ErrNotFound   = errors.New("challenge not found")
+	ErrNotPending = errors.New("challenge is no longer pending")
+	ErrExpired    = errors.New("challenge has expired")
+)
+
+type Service struct {
+	events *events.Dispatcher
+}
+
+func NewService(dispatcher *events.Dispatcher) *Service {
+	return &Service{events: dispatcher}
+}
+
+// Create challenges the user called username. color is the challenger's
+// side: "white", "black", or "random"/"" for a coin toss when accepted.
+func (s *Service) Create(challengerID uint, username, timeControl, color string, rated bool) (*models.Challenge, error) {
+	tc, err := game.ParseTimeControl(timeControl)
+	if err != nil {
+		return nil, err
+	}
+	switch color {
+	case "white", "black", "random":
+	case "":
+		color = "random"
+	default:
+		return nil, fmt.Errorf("invalid color %q", color)
+	}
+
+	var challenger, challenged models.User
+	if err := database.GetDB().Where("id = ?", challengerID).First(&challenger).Error; err != nil {
+		return nil, err
+	}
+	err = database.GetDB().Where("username = ? AND is_bot = ? AND is_banned = ?", username, false, false).First(&challenged).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("user %q not found", username)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if challenged.ID == challengerID {
+		return nil, errors.New("you cannot challenge yourself")
+	}
+
+	s.expireStale()
+	var open int64
+	database.GetDB().Model(&models.Challenge{}).
+		Where("challenger_id = ? AND challenged_id = ? AND status = ?", challengerID, challenged.ID, "pending").
+		Count(&open)
+	if open > 0 {
+		return nil, fmt.Errorf("you already have a pending challenge to %s", username)
+	}
+
+	ch := &models.Challenge{
+		ID:           uuid.NewString(),
+		ChallengerID: challengerID,
+		ChallengedID: challenged.ID,
+		TimeControl:  timeControl,
+		Mode:         tc.Mode(),
+		Color:        color,
+		Rated:        rated,
+		Status:       "pending",
+		ExpiresAt:    time.Now().Add(challengeTTL),
+	}
+	if err := database.GetDB().Omit("Challenger", "Challenged").Create(ch).Error; err != nil {
+		return nil, err
+	}
+	ch.Challenger, ch.Challenged = challenger, challenged
+
+	s.events.Publish(challenged.ID, events.ChallengeReceived, payload(ch))
+	return ch, nil
+}
+
+// List returns the user's pending challenges, sent and received, newest
+// first.
+func (s *Service) List(userID uint) ([]models.Challenge, error) {
+	s.expireStale()
+	var challenges []models.Challenge
+	err := database.GetDB().
+		Preload("Challenger").
+		Preload("Challenged").
+		Where("(challenger_id = ? OR challenged_id = ?) AND status = ?", userID, userID, "pending").
+		Order("created_at DESC").
+		Find(&challenges).Error
+	return challenges, err
+}
+
+// Accept starts the game for a challenge sent to userID. The game is
+// created the same way matchmaking creates one.
+func (s *Service) Accept(userID uint, id string) (*models.Challenge, *models.Game, error) {
+	var ch models.Challenge
+	var g *models.Game
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := respondable(tx, &ch, id, userID); err != nil {
+			return err
+		}
+
+		whiteID, blackID := ch.ChallengerID, ch.ChallengedID
+		if ch.Color == "black" || (ch.Color == "random" && rand.Intn(2) == 1) {
+			whiteID, blackID = blackID, whiteID
+		}
+		var err error
+		g, err = matchmaking.CreateGame(tx, whiteID, blackID, ch.Mode, ch.TimeControl, ch.Rated)
+		if err != nil {
+			return err
+		}
+		return respond(tx, &ch, "accepted", g.ID)
+	})
+	if errors.Is(err, ErrExpired) {
+		s.expireStale()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.events.MatchFound(g)
+	return &ch, g, nil
+}
+
+// Decline turns down a challenge sent to userID.
+func (s *Service) Decline(userID uint, id string) (*models.Challenge, error) {
+	var ch models.Challenge
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := respondable(tx, &ch, id, userID); err != nil {
+			return err
+		}
+		return respond(tx, &ch, "declined", "")
+	})
+	if errors.Is(err, ErrExpired) {
+		s.expireStale()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ch.ChallengerID, events.ChallengeDeclined, payload(&ch))
+	return &ch, nil
+}
+
+// Cancel withdraws a challenge userID sent.
+func (s *Service) Cancel(userID uint, id string) (*models.Challenge, error) {
+	var ch models.Challenge
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := lock(tx, &ch, id); err != nil {
+			return err
+		}
+		if ch.ChallengerID != userID {
+			return ErrNotFound
+		}
+		if ch.Status != "pending" {
+			return ErrNotPending
+		}
+		return respond(tx, &ch, "cancelled", "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ch.ChallengedID, events.ChallengeCancelled, payload(&ch))
+	return &ch, nil
+}
+
+// lock loads a challenge with its users and locks its row until the
+// transaction ends, so two answers can't both succeed.
+func lock(tx *gorm.DB, ch *models.Challenge, id string) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(ch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Where("id = ?", ch.ChallengerID).First(&ch.Challenger).Error; err != nil {
+		return err
+	}
+	return tx.Where("id = ?", ch.ChallengedID).First(&ch.Challenged).Error
+}
+
+// respondable locks a challenge and checks userID may still answer it.
+// Challenges other users received are reported as not found. On ErrExpired
+// the caller marks it expired once the lock is released.
+func respondable(tx *gorm.DB, ch *models.Challenge, id string, userID uint) error {
+	if err := lock(tx, ch, id); err != nil {
+		return err
+	}
+	if ch.ChallengedID != userID {
+		return ErrNotFound
+	}
+	if ch.Status != "pending" {
+		return ErrNotPending
+	}
+	if time.Now().After(ch.ExpiresAt) {
+		return ErrExpired
+	}
+	return nil
+}
+
+func respond(tx *gorm.DB, ch *models.Challenge, status, gameID string) error {
+	now := time.Now()
+	ch.Status, ch.GameID, ch.RespondedAt = status, gameID, &now
+	return tx.Model(&models.Challenge{}).Where("id = ?", ch.ID).Updates(map[string]interface{}{
+		"status":       status,
+		"game_id":      gameID,
+		"responded_at": now,
+	}).Error
+}
+
+// expireStale marks pending challenges past their expiry as expired.
+func (s *Service) expireStale() {
+	database.GetDB().Model(&models.Challenge{}).
+		Where("status = ? AND expires_at < ?", "pending", time.Now()).
+		Update("status", "expired")
+}
+
+func payload(ch *models.Challenge) events.ChallengePayload {
+	return events.ChallengePayload{
+		ID:          ch.ID,
+		Challenger:  ch.Challenger.Username,
+		Challenged:  ch.Challenged.Username,
+		TimeControl: ch.TimeControl,
+		Mode:        ch.Mode,
+		Color:       ch.Color,
+		Rated:       ch.Rated,
+		ExpiresAt:   ch.ExpiresAt,
+	}
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Score is an evaluation from the side to move's point of view: either
+// centipawns or, if Mate is non-zero, moves to mate (negative when the
+// side to move is getting mated).
+type Score struct {
+	CP         int
+	Mate       int
+	LowerBound bool
+	UpperBound bool
+}
+
+// IsMate reports whether the score is a forced mate.
+func (s Score) IsMate() bool {
+	return s.Mate != 0
+}
+
+// Info is one parsed "info" line from a search.
+type Info struct {
+	Depth    int
+	SelDepth int
+	MultiPV  int // 1 for the best line
+	Score    Score
+	HasScore bool
+	Nodes    int64
+	NPS      int64
+	HashFull int // permille
+	Time     time.Duration
+	PV       []string
+}
+
+// infoKeywords are the tokens that start a field of an info line.
+var infoKeywords = map[string]bool{
+	"depth": true, "seldepth": true, "time": true, "nodes": true, "pv": true,
+	"multipv": true, "score": true, "currmove": true, "currmovenumber": true,
+	"hashfull": true, "nps": true, "tbhits": true, "sbhits": true, "cpuload": true,
+	"string": true, "refutation": true, "currline": true,
+}
+
+// ParseInfo parses a UCI info line. It reports false for lines that carry
+// no search data, such as "info string ...".
+func ParseInfo(line string) (Info, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "info" {
+		return Info{}, false
+	}
+
+	info := Info{MultiPV: 1}
+	useful := false
+	for i := 1; i < len(fields); i++ {
+		next := func() int64 {
+			if i+1 >= len(fields) {
+				return 0
+			}
+			i++
+			n, _ := strconv.ParseInt(fields[i], 10, 64)
+			return n
+		}
+
+		switch fields[i] {
+		case "string":
+			// Free text runs to the end of the line.
+			return info, useful
+		case "depth":
+			info.Depth = int(next())
+			useful = true
+		case "seldepth":
+			info.SelDepth = int(next())
+		case "multipv":
+			info.MultiPV = int(next())
+		case "nodes":
+			info.Nodes = next()
+		case "nps":
+			info.NPS = next()
+		case "hashfull":
+			info.HashFull = int(next())
+		case "time":
+			info.Time = time.Duration(next()) * time.Millisecond
+		case "score":
+			info.HasScore = true
+			useful = true
+		case "cp":
+			info.Score.CP = int(next())
+		case "mate":
+			info.Score.Mate = int(next())
+		case "lowerbound":
+			info.Score.LowerBound = true
+		case "upperbound":
+			info.Score.UpperBound = true
+		case "pv":
+			info.PV = append([]string{}, fields[i+1:]...)
+			return info, true
+		case "currmove", "currmovenumber", "tbhits", "cpuload", "sbhits":
+			// Not tracked; skip the value.
+			next()
+		case "refutation", "currline":
+			// Not tracked; skip the moves, which run to the next keyword.
+			for i+1 < len(fields) && !infoKeywords[fields[i+1]] {
+				i++
+			}
+		}
+	}
+	return info, useful
+}
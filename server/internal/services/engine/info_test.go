@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		line string
+		want Info
+		ok   bool
+	}{
+		{
+			line: "info depth 12 seldepth 18 multipv 1 score cp 34 nodes 150000 nps 1200000 hashfull 45 time 125 pv e2e4 e7e5 g1f3",
+			want: Info{Depth: 12, SelDepth: 18, MultiPV: 1, Score: Score{CP: 34}, HasScore: true,
+				Nodes: 150000, NPS: 1200000, HashFull: 45, Time: 125 * time.Millisecond, PV: []string{"e2e4", "e7e5", "g1f3"}},
+			ok: true,
+		},
+		{
+			line: "info depth 20 score mate -3 pv h7h8 g8h8",
+			want: Info{Depth: 20, MultiPV: 1, Score: Score{Mate: -3}, HasScore: true, PV: []string{"h7h8", "g8h8"}},
+			ok:   true,
+		},
+		{
+			line: "info depth 9 multipv 3 score cp -120 pv d2d4",
+			want: Info{Depth: 9, MultiPV: 3, Score: Score{CP: -120}, HasScore: true, PV: []string{"d2d4"}},
+			ok:   true,
+		},
+		{
+			line: "info depth 15 score cp 50 lowerbound nodes 900",
+			want: Info{Depth: 15, MultiPV: 1, Score: Score{CP: 50, LowerBound: true}, HasScore: true, Nodes: 900},
+			ok:   true,
+		},
+		{
+			line: "info depth 15 score cp 10 upperbound",
+			want: Info{Depth: 15, MultiPV: 1, Score: Score{CP: 10, UpperBound: true}, HasScore: true},
+			ok:   true,
+		},
+		{
+			line: "info depth 4 currmove e2e4 currmovenumber 1",
+			want: Info{Depth: 4, MultiPV: 1},
+			ok:   true,
+		},
+		{
+			line: "info depth 6 refutation d1h5 g6h5 score cp 25 currline 1 e2e4 e7e5 nodes 300 pv g1f3 b8c6",
+			want: Info{Depth: 6, MultiPV: 1, Score: Score{CP: 25}, HasScore: true, Nodes: 300, PV: []string{"g1f3", "b8c6"}},
+			ok:   true,
+		},
+		{
+			line: "info currline 2 d2d4 d7d5 c2c4 multipv 2 depth 3 refutation e2e4",
+			want: Info{Depth: 3, MultiPV: 2},
+			ok:   true,
+		},
+		{
+			line: "info string NNUE evaluation using nn-1234.nnue depth 30 score cp 99",
+			want: Info{MultiPV: 1},
+		},
+		{line: "info nodes 1000 nps 5000", want: Info{MultiPV: 1, Nodes: 1000, NPS: 5000}},
+		{line: "bestmove e2e4 ponder e7e5"},
+		{line: "info"},
+	}
+	for _, tt := range tests {
+		got, ok := ParseInfo(tt.line)
+		if ok != tt.ok {
+			t.Errorf("ParseInfo(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseInfo(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestScoreIsMate(t *testing.T) {
+	if (Score{CP: 300}).IsMate() {
+		t.Error("centipawn score reported as mate")
+	}
+	if !(Score{Mate: -2}).IsMate() {
+		t.Error("mate score not reported as mate")
+	}
+}
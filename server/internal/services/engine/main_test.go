@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// Paths to cmd/fakeuci, built once for the package's tests. stubbornPath
+// runs it with -ignore-stop, for the paths where an engine must be killed.
+var fakePath, stubbornPath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "fakeuci")
+	if err != nil {
+		panic(err)
+	}
+	code := run(m, dir)
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func run(m *testing.M, dir string) int {
+	fakePath = filepath.Join(dir, "fakeuci")
+	build := exec.Command("go", "build", "-o", fakePath, "github.com/datmedevil17/chesss/cmd/fakeuci")
+	if out, err := build.CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "building fakeuci: %v\n%s", err, out)
+		return 1
+	}
+
+	// NewEngine takes no arguments, so flags go through a wrapper script.
+	stubbornPath = filepath.Join(dir, "fakeuci-ignore-stop")
+	script := fmt.Sprintf("#!/bin/sh\nexec %q -ignore-stop \"$@\"\n", fakePath)
+	if err := os.WriteFile(stubbornPath, []byte(script), 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return m.Run()
+}
+
+// newTestEngine starts the engine at path and closes it when t ends.
+func newTestEngine(t *testing.T, path string) *Engine {
+	t.Helper()
+	e, err := NewEngine(path)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	t.Cleanup(e.Close)
+	return e
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	// How often idle engines are checked, and how long they get to answer.
+	healthCheckPeriod  = 30 * time.Second
+	healthCheckTimeout = 5 * time.Second
+)
+
+// Pool shares a bounded number of engine processes between callers.
+// Engines are started on demand, checked while idle, and replaced when
+// they crash; callers beyond the limit queue until one is free.
+type Pool struct {
+	path  string
+	slots chan struct{} // one token per engine in use
+	idle  chan *Engine
+	done  chan struct{}
+}
+
+// NewPool returns a pool running at most size engines from path.
+func NewPool(path string, size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	p := &Pool{
+		path:  path,
+		slots: make(chan struct{}, size),
+		idle:  make(chan *Engine, size),
+		done:  make(chan struct{}),
+	}
+	go p.healthLoop()
+	return p
+}
+
+// Acquire waits for a free engine, starting one if none is idle. It gives
+// up when ctx ends. Every engine acquired must be released.
+func (p *Pool) Acquire(ctx context.Context) (*Engine, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		select {
+		case e := <-p.idle:
+			if e.Alive() {
+				return e, nil
+			}
+			e.Close()
+		default:
+			e, err := NewEngine(p.path)
+			if err != nil {
+				<-p.slots
+				return nil, err
+			}
+			return e, nil
+		}
+	}
+}
+
+// Release returns an engine to the pool. Engines that have died are
+// dropped and replaced by the next Acquire.
+func (p *Pool) Release(e *Engine) {
+	defer func() { <-p.slots }()
+
+	if !e.Alive() {
+		log.Printf("Engine exited; it will be restarted on demand")
+		e.Close()
+		return
+	}
+	select {
+	case <-p.done:
+		e.Close()
+		return
+	default:
+	}
+	select {
+	case p.idle <- e:
+	default:
+		e.Close()
+	}
+}
+
+// Do runs fn with an engine from the pool.
+func (p *Pool) Do(ctx context.Context, fn func(*Engine) error) error {
+	e, err := p.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Release(e)
+	return fn(e)
+}
+
+// Close stops health checks and shuts down idle engines. Engines still in
+// use are closed when released.
+func (p *Pool) Close() {
+	close(p.done)
+	for {
+		select {
+		case e := <-p.idle:
+			e.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (p *Pool) healthLoop() {
+	ticker := time.NewTicker(healthCheckPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.checkIdle()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// checkIdle pings each idle engine once and drops any that fail.
+func (p *Pool) checkIdle() {
+	for n := len(p.idle); n > 0; n-- {
+		var e *Engine
+		select {
+		case e = <-p.idle:
+		default:
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		err := e.Ping(ctx)
+		cancel()
+		if err != nil || !e.Alive() {
+			log.Printf("Dropping unhealthy engine: %v", err)
+			e.Close()
+			continue
+		}
+		select {
+		case p.idle <- e:
+		default:
+			e.Close()
+		}
+	}
+}
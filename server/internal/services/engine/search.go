@@ -0,0 +1,264 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SearchParams describes one search. Depth, MoveTime, Nodes or the clock
+// fields bound it; Infinite and Ponder searches run until stopped. With
+// none of these the engine thinks for a second.
+type SearchParams struct {
+	FEN   string   // Starting position; empty means the standard one
+	Moves []string // UCI moves played from FEN
+
+	Depth    int
+	Nodes    int64
+	MoveTime time.Duration
+
+	// Clock state, for letting the engine manage its own time.
+	WTime, BTime time.Duration
+	WInc, BInc   time.Duration
+	MovesToGo    int
+
+	Infinite bool
+	Ponder   bool // Search the position after Moves as if pondering; see Search.PonderHit
+	MultiPV  int  // Number of lines to report; 0 leaves the engine's setting
+
+	// When the engine last searched for a different game it is sent
+	// ucinewgame first, so shared engines don't carry hash entries over.
+	GameID string
+}
+
+// goCommand renders the UCI go command for p.
+func (p SearchParams) goCommand() string {
+	var b strings.Builder
+	b.WriteString("go")
+	if p.Ponder {
+		b.WriteString(" ponder")
+	}
+	if p.WTime > 0 || p.BTime > 0 {
+		fmt.Fprintf(&b, " wtime %d btime %d", p.WTime.Milliseconds(), p.BTime.Milliseconds())
+		if p.WInc > 0 || p.BInc > 0 {
+			fmt.Fprintf(&b, " winc %d binc %d", p.WInc.Milliseconds(), p.BInc.Milliseconds())
+		}
+		if p.MovesToGo > 0 {
+			fmt.Fprintf(&b, " movestogo %d", p.MovesToGo)
+		}
+	}
+	if p.Depth > 0 {
+		fmt.Fprintf(&b, " depth %d", p.Depth)
+	}
+	if p.Nodes > 0 {
+		fmt.Fprintf(&b, " nodes %d", p.Nodes)
+	}
+	if p.MoveTime > 0 {
+		fmt.Fprintf(&b, " movetime %d", p.MoveTime.Milliseconds())
+	}
+	if p.Infinite {
+		b.WriteString(" infinite")
+	}
+	if b.Len() == len("go") {
+		b.WriteString(" movetime 1000")
+	}
+	return b.String()
+}
+
+// SearchResult is the outcome of a finished search.
+type SearchResult struct {
+	BestMove string
+	Ponder   string // The reply the engine expects, if it said
+	Lines    []Info // Latest info for each MultiPV line, best first
+}
+
+// Search is a running search. Updates streams each info line as it
+// arrives and is closed when the search ends; Wait returns the result.
+type Search struct {
+	Updates <-chan Info
+
+	engine   *Engine
+	updates  chan Info
+	done     chan struct{}
+	stopOnce sync.Once
+	result   SearchResult
+	err      error
+}
+
+// Search starts a search and returns at once. The engine is busy until the
+// search ends: when its limits are reached, on Stop, or when ctx ends. An
+// engine that doesn't stop in time is killed. Updates is buffered; info
+// lines that arrive while it is full are dropped, but the result always
+// has the final lines.
+func (e *Engine) Search(ctx context.Context, p SearchParams) (*Search, error) {
+	e.mu.Lock()
+	if err := e.prepare(ctx, p); err != nil {
+		e.mu.Unlock()
+		return nil, err
+	}
+
+	s := &Search{
+		engine:  e,
+		updates: make(chan Info, 64),
+		done:    make(chan struct{}),
+	}
+	s.Updates = s.updates
+	go s.run(ctx)
+	return s, nil
+}
+
+// prepare sends everything up to and including go. Callers hold mu.
+func (e *Engine) prepare(ctx context.Context, p SearchParams) error {
+	if p.GameID == "" || p.GameID != e.gameID {
+		if err := e.send("ucinewgame"); err != nil {
+			return err
+		}
+		if err := e.ready(ctx); err != nil {
+			// A late readyok would confuse the next command.
+			e.Close()
+			return err
+		}
+		e.gameID = p.GameID
+	}
+
+	if p.MultiPV > 0 {
+		v := fmt.Sprint(p.MultiPV)
+		if e.options["MultiPV"] != v {
+			if err := e.send("setoption name MultiPV value " + v); err != nil {
+				return err
+			}
+			e.options["MultiPV"] = v
+		}
+	}
+
+	cmd := "position startpos"
+	if p.FEN != "" {
+		cmd = "position fen " + p.FEN
+	}
+	if len(p.Moves) > 0 {
+		cmd += " moves " + strings.Join(p.Moves, " ")
+	}
+	if err := e.send(cmd); err != nil {
+		return err
+	}
+	return e.send(p.goCommand())
+}
+
+// run reads the search's output until bestmove, then frees the engine.
+func (s *Search) run(ctx context.Context) {
+	e := s.engine
+	defer e.mu.Unlock()
+	defer close(s.done)
+	defer close(s.updates)
+
+	lines := map[int]Info{}
+	var stopDeadline <-chan time.Time
+	cancelled := ctx.Done()
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				s.err = ErrEngineExited
+				return
+			}
+			if info, ok := ParseInfo(line); ok {
+				if len(info.PV) > 0 {
+					lines[info.MultiPV] = info
+				}
+				select {
+				case s.updates <- info:
+				default:
+				}
+				continue
+			}
+			if strings.HasPrefix(line, "bestmove") {
+				s.result = parseBestMove(line, lines)
+				if s.result.BestMove == "" {
+					s.err = fmt.Errorf("invalid bestmove line: %s", line)
+				}
+				if ctx.Err() != nil {
+					s.err = ctx.Err()
+				}
+				return
+			}
+		case <-cancelled:
+			cancelled = nil
+			s.Stop()
+			stopDeadline = time.After(stopTimeout)
+		case <-stopDeadline:
+			e.Close()
+			s.err = fmt.Errorf("engine did not stop: %w", ctx.Err())
+			return
+		}
+	}
+}
+
+// Stop asks the engine to finish the search now; it still reports a move.
+func (s *Search) Stop() {
+	s.stopOnce.Do(func() {
+		if err := s.engine.send("stop"); err != nil {
+			s.engine.Close()
+		}
+	})
+}
+
+// PonderHit tells a pondering engine the expected move was played, turning
+// the ponder search into a normal one.
+func (s *Search) PonderHit() error {
+	return s.engine.send("ponderhit")
+}
+
+// Done is closed when the search has ended.
+func (s *Search) Done() <-chan struct{} {
+	return s.done
+}
+
+// Wait blocks until the search ends and returns its result.
+func (s *Search) Wait() (SearchResult, error) {
+	<-s.done
+	return s.result, s.err
+}
+
+// BestMove runs a search to completion and returns the engine's move in UCI
+// notation.
+func (e *Engine) BestMove(ctx context.Context, p SearchParams) (string, error) {
+	s, err := e.Search(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	res, err := s.Wait()
+	if err != nil {
+		return "", err
+	}
+	return res.BestMove, nil
+}
+
+// Analyze runs a search to completion and returns the full result.
+func (e *Engine) Analyze(ctx context.Context, p SearchParams) (SearchResult, error) {
+	s, err := e.Search(ctx, p)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	return s.Wait()
+}
+
+func parseBestMove(line string, lines map[int]Info) SearchResult {
+	var res SearchResult
+	fields := strings.Fields(line)
+	if len(fields) >= 2 && fields[1] != "(none)" {
+		res.BestMove = fields[1]
+	}
+	if len(fields) >= 4 && fields[2] == "ponder" {
+		res.Ponder = fields[3]
+	}
+	for i := 1; ; i++ {
+		info, ok := lines[i]
+		if !ok {
+			break
+		}
+		res.Lines = append(res.Lines, info)
+	}
+	return res
+}
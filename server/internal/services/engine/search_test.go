@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSearchStreamsUpdates(t *testing.T) {
+	e := newTestEngine(t, fakePath)
+
+	s, err := e.Search(context.Background(), SearchParams{Depth: 5, MultiPV: 2})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	var updates []Info
+	for info := range s.Updates {
+		updates = append(updates, info)
+	}
+	res, err := s.Wait()
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	// fakeuci reports every line at every depth, in order.
+	if len(updates) != 10 {
+		t.Fatalf("got %d updates, want 10: %+v", len(updates), updates)
+	}
+	for i, info := range updates {
+		if depth, pv := i/2+1, i%2+1; info.Depth != depth || info.MultiPV != pv {
+			t.Errorf("update %d is depth %d line %d, want depth %d line %d", i, info.Depth, info.MultiPV, depth, pv)
+		}
+		if !info.HasScore || len(info.PV) == 0 {
+			t.Errorf("update %d has no score or pv: %+v", i, info)
+		}
+	}
+
+	if res.BestMove == "" || res.BestMove != res.Lines[0].PV[0] {
+		t.Errorf("best move %q doesn't match the first line %v", res.BestMove, res.Lines[0].PV)
+	}
+	if len(res.Lines) != 2 || res.Lines[0].Depth != 5 || res.Lines[1].MultiPV != 2 {
+		t.Errorf("result lines = %+v, want the depth 5 info for lines 1 and 2", res.Lines)
+	}
+}
+
+func TestStopEndsInfiniteSearch(t *testing.T) {
+	e := newTestEngine(t, fakePath)
+
+	s, err := e.Search(context.Background(), SearchParams{Infinite: true})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	select {
+	case <-s.Updates:
+	case <-time.After(time.Second):
+		t.Fatal("no update from a running search")
+	}
+	select {
+	case <-s.Done():
+		t.Fatal("infinite search ended on its own")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	s.Stop()
+	select {
+	case <-s.Done():
+	case <-time.After(stopTimeout):
+		t.Fatal("search did not end after stop")
+	}
+	res, err := s.Wait()
+	if err != nil || res.BestMove == "" {
+		t.Fatalf("Wait = %+v, %v; want a best move", res, err)
+	}
+
+	// The engine is free for the next search.
+	if !e.Alive() {
+		t.Fatal("engine died after stop")
+	}
+	if _, err := e.BestMove(context.Background(), SearchParams{Depth: 1}); err != nil {
+		t.Fatalf("BestMove after stop: %v", err)
+	}
+}
+
+func TestEngineIgnoringStopIsKilled(t *testing.T) {
+	e := newTestEngine(t, stubbornPath)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := e.Search(ctx, SearchParams{Infinite: true})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	select {
+	case <-s.Updates:
+	case <-time.After(time.Second):
+		t.Fatal("no update from a running search")
+	}
+
+	cancel()
+	select {
+	case <-s.Done():
+	case <-time.After(stopTimeout + time.Second):
+		t.Fatal("search outlived the stop timeout")
+	}
+	if _, err := s.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait error = %v, want context.Canceled", err)
+	}
+	if e.Alive() {
+		t.Error("engine that ignored stop is still alive")
+	}
+	if _, err := e.BestMove(context.Background(), SearchParams{Depth: 1}); err == nil {
+		t.Error("killed engine ran another search")
+	}
+}
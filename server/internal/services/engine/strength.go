@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// Strength is how an engine is handicapped for one bot difficulty level.
+type Strength struct {
+	SkillLevel int           // Stockfish "Skill Level", 0-20
+	Elo        int           // UCI_Elo; 0 plays at full strength
+	MoveTime   time.Duration // Thinking time per move
+}
+
+const (
+	MinLevel = 1
+	MaxLevel = 8
+)
+
+var levels = [MaxLevel]Strength{
+	{SkillLevel: 0, Elo: 1350, MoveTime: 50 * time.Millisecond},
+	{SkillLevel: 3, Elo: 1500, MoveTime: 100 * time.Millisecond},
+	{SkillLevel: 6, Elo: 1700, MoveTime: 150 * time.Millisecond},
+	{SkillLevel: 9, Elo: 1900, MoveTime: 200 * time.Millisecond},
+	{SkillLevel: 12, Elo: 2100, MoveTime: 300 * time.Millisecond},
+	{SkillLevel: 15, Elo: 2300, MoveTime: 500 * time.Millisecond},
+	{SkillLevel: 18, Elo: 2600, MoveTime: 800 * time.Millisecond},
+	{SkillLevel: 20, MoveTime: 1500 * time.Millisecond},
+}
+
+// LevelStrength returns the engine settings for a difficulty level from
+// MinLevel to MaxLevel.
+func LevelStrength(level int) (Strength, error) {
+	if level < MinLevel || level > MaxLevel {
+		return Strength{}, fmt.Errorf("difficulty must be between %d and %d", MinLevel, MaxLevel)
+	}
+	return levels[level-1], nil
+}
@@ -2,19 +2,42 @@ package engine
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const (
+	// How long a new engine gets to answer uci and isready.
+	handshakeTimeout = 10 * time.Second
+
+	// How long a search gets to report bestmove after "stop".
+	stopTimeout = 2 * time.Second
+)
+
+// ErrEngineExited is returned once the engine process has gone away.
+var ErrEngineExited = errors.New("engine exited")
+
+// Engine is one UCI engine process. Output is read by a background
+// goroutine so that every wait can be cancelled or time out.
 type Engine struct {
-	cmd    *exec.Cmd
-	stdin  *bufio.Writer
-	stdout *bufio.Scanner
-	mu     sync.Mutex
+	cmd     *exec.Cmd
+	stdin   *bufio.Writer
+	lines   chan string   // stdout, one line at a time; closed at EOF
+	exited  chan struct{} // closed when the process ends
+	killed  atomic.Bool
+	mu      sync.Mutex        // held for a whole exchange, e.g. a search
+	wmu     sync.Mutex        // guards stdin, which Search.Stop writes mid-search
+	options map[string]string // values already set, so repeats are skipped
+	gameID  string            // game of the last search, see SearchParams.GameID
 }
 
+// NewEngine starts the engine at path and completes the UCI handshake.
 func NewEngine(path string) (*Engine, error) {
 	cmd := exec.Command(path)
 	stdin, err := cmd.StdinPipe()
@@ -30,90 +53,152 @@ func NewEngine(path string) (*Engine, error) {
 		return nil, err
 	}
 
-	return &Engine{
-		cmd:    cmd,
-		stdin:  bufio.NewWriter(stdin),
-		stdout: bufio.NewScanner(stdout),
-	}, nil
+	e := &Engine{
+		cmd:     cmd,
+		stdin:   bufio.NewWriter(stdin),
+		lines:   make(chan string, 256),
+		exited:  make(chan struct{}),
+		options: make(map[string]string),
+	}
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			e.lines <- scanner.Text()
+		}
+		close(e.lines)
+	}()
+	go func() {
+		cmd.Wait()
+		close(e.exited)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	if err := e.handshake(ctx); err != nil {
+		e.Close()
+		return nil, fmt.Errorf("uci handshake: %w", err)
+	}
+	return e, nil
 }
 
-func (e *Engine) SendCommand(cmd string) error {
+func (e *Engine) handshake(ctx context.Context) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	_, err := e.stdin.WriteString(cmd + "\n")
-	if err != nil {
+	if err := e.send("uci"); err != nil {
 		return err
 	}
-	return e.stdin.Flush()
+	if _, err := e.waitFor(ctx, "uciok"); err != nil {
+		return err
+	}
+	return e.ready(ctx)
 }
 
-func (e *Engine) GetBestMove(fen string, depth int) (string, error) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+// send writes one command. Callers hold mu, except for commands that
+// steer a running search.
+func (e *Engine) send(cmd string) error {
+	e.wmu.Lock()
+	defer e.wmu.Unlock()
+	if _, err := e.stdin.WriteString(cmd + "\n"); err != nil {
+		return err
+	}
+	return e.stdin.Flush()
+}
 
-	// Reset and set position
-	e.stdin.WriteString("ucinewgame\n")
-	e.stdin.WriteString(fmt.Sprintf("position fen %s\n", fen))
-	e.stdin.WriteString(fmt.Sprintf("go depth %d\n", depth))
-	e.stdin.Flush()
-
-	// Read output until bestmove
-	for e.stdout.Scan() {
-		line := e.stdout.Text()
-		if strings.HasPrefix(line, "bestmove") {
-			parts := strings.Split(line, " ")
-			if len(parts) >= 2 {
-				return parts[1], nil
+// waitFor discards output until a line starting with prefix. Callers hold mu.
+func (e *Engine) waitFor(ctx context.Context, prefix string) (string, error) {
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return "", ErrEngineExited
 			}
-			return "", fmt.Errorf("invalid bestmove line: %s", line)
+			if strings.HasPrefix(line, prefix) {
+				return line, nil
+			}
+		case <-ctx.Done():
+			return "", ctx.Err()
 		}
 	}
+}
 
-	if err := e.stdout.Err(); err != nil {
-		return "", err
+// ready sends isready and waits for readyok. Callers hold mu.
+func (e *Engine) ready(ctx context.Context) error {
+	if err := e.send("isready"); err != nil {
+		return err
 	}
+	_, err := e.waitFor(ctx, "readyok")
+	return err
+}
 
-	return "", fmt.Errorf("engine closed unexpectedly")
+func (e *Engine) SendCommand(cmd string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.send(cmd)
 }
 
-func (e *Engine) GetBestMoveFromHistory(moves []string, depth int) (string, error) {
+// Ping checks that the engine still answers isready.
+func (e *Engine) Ping(ctx context.Context) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	return e.ready(ctx)
+}
 
-	// Reset and set position with history
-	// "position startpos moves e2e4 e7e5 ..."
-	cmd := "position startpos"
-	if len(moves) > 0 {
-		cmd += " moves " + strings.Join(moves, " ")
-	}
-
-	e.stdin.WriteString("ucinewgame\n")
-	e.stdin.WriteString(cmd + "\n")
-	e.stdin.WriteString(fmt.Sprintf("go depth %d\n", depth))
-	e.stdin.Flush()
-
-	// Read output until bestmove
-	for e.stdout.Scan() {
-		line := e.stdout.Text()
-		if strings.HasPrefix(line, "bestmove") {
-			parts := strings.Split(line, " ")
-			if len(parts) >= 2 {
-				return parts[1], nil
-			}
-			return "", fmt.Errorf("invalid bestmove line: %s", line)
-		}
+// SetOption sends a UCI setoption command unless the option already has
+// that value.
+func (e *Engine) SetOption(name string, value interface{}) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	v := fmt.Sprint(value)
+	if current, ok := e.options[name]; ok && current == v {
+		return nil
+	}
+	if err := e.send(fmt.Sprintf("setoption name %s value %s", name, v)); err != nil {
+		return err
 	}
+	e.options[name] = v
+	return nil
+}
 
-	if err := e.stdout.Err(); err != nil {
-		return "", err
+// SetStrength applies a bot difficulty's handicap to the engine.
+func (e *Engine) SetStrength(s Strength) error {
+	if err := e.SetOption("Skill Level", s.SkillLevel); err != nil {
+		return err
 	}
+	if s.Elo == 0 {
+		return e.SetOption("UCI_LimitStrength", false)
+	}
+	if err := e.SetOption("UCI_LimitStrength", true); err != nil {
+		return err
+	}
+	return e.SetOption("UCI_Elo", s.Elo)
+}
+
+func (e *Engine) GetBestMove(fen string, depth int) (string, error) {
+	return e.BestMove(context.Background(), SearchParams{FEN: fen, Depth: depth})
+}
+
+func (e *Engine) GetBestMoveFromHistory(moves []string, depth int) (string, error) {
+	return e.BestMove(context.Background(), SearchParams{Moves: moves, Depth: depth})
+}
 
-	return "", fmt.Errorf("engine closed unexpectedly")
+// Alive reports whether the engine process is still running.
+func (e *Engine) Alive() bool {
+	if e.killed.Load() {
+		return false
+	}
+	select {
+	case <-e.exited:
+		return false
+	default:
+		return true
+	}
 }
 
 func (e *Engine) Close() {
-	if e.cmd != nil {
+	e.killed.Store(true)
+	if e.cmd != nil && e.cmd.Process != nil {
 		e.cmd.Process.Kill()
 	}
 }
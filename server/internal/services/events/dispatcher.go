@@ -0,0 +1,143 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/models"
+)
+
+type Type string
+
+const (
+	MatchFound         Type = "match_found"
+	ChallengeReceived  Type = "challenge_received"
+	ChallengeDeclined  Type = "challenge_declined"
+	ChallengeCancelled Type = "challenge_cancelled"
+	GameFinished       Type = "game_finished"
+)
+
+// How many events a subscriber may fall behind before new ones are dropped.
+const subscriberBuffer = 32
+
+// Event is one notification for one user.
+type Event struct {
+	Type    Type        `json:"type"`
+	Payload interface{} `json:"payload"`
+	Time    time.Time   `json:"time"`
+}
+
+type MatchFoundPayload struct {
+	GameID      string `json:"game_id"`
+	Color       string `json:"color"` // The recipient's side
+	Mode        string `json:"mode"`
+	TimeControl string `json:"time_control"`
+	Rated       bool   `json:"rated"`
+}
+
+// ChallengePayload describes a challenge to either of its two users.
+type ChallengePayload struct {
+	ID          string    `json:"id"`
+	Challenger  string    `json:"challenger"` // Username
+	Challenged  string    `json:"challenged"`
+	TimeControl string    `json:"time_control"`
+	Mode        string    `json:"mode"`
+	Color       string    `json:"color"` // The challenger's side: white | black | random
+	Rated       bool      `json:"rated"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type GameFinishedPayload struct {
+	GameID string `json:"game_id"`
+	Result string `json:"result"` // "1-0", "0-1", "1/2-1/2", or "*" if aborted
+	Reason string `json:"reason"`
+	Winner string `json:"winner"` // "white", "black", "" (for draw)
+}
+
+// Dispatcher routes events to the connections of the users they are for.
+// Services publish into it; the /events stream subscribes. A user may hold
+// several subscriptions, e.g. one per open tab, and each gets every event.
+type Dispatcher struct {
+	mu   sync.RWMutex
+	subs map[uint]map[*Subscription]bool
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{subs: make(map[uint]map[*Subscription]bool)}
+}
+
+// Subscription receives one user's events on C until it is closed.
+type Subscription struct {
+	C <-chan Event
+
+	ch         chan Event
+	userID     uint
+	dispatcher *Dispatcher
+	once       sync.Once
+}
+
+// Subscribe starts delivering userID's events. The caller must Close the
+// subscription when done.
+func (d *Dispatcher) Subscribe(userID uint) *Subscription {
+	s := &Subscription{
+		ch:         make(chan Event, subscriberBuffer),
+		userID:     userID,
+		dispatcher: d,
+	}
+	s.C = s.ch
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.subs[userID] == nil {
+		d.subs[userID] = make(map[*Subscription]bool)
+	}
+	d.subs[userID][s] = true
+	return s
+}
+
+// Close stops delivery and closes C. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		d := s.dispatcher
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.subs[s.userID], s)
+		if len(d.subs[s.userID]) == 0 {
+			delete(d.subs, s.userID)
+		}
+		close(s.ch)
+	})
+}
+
+// Publish sends an event to every subscription userID has. It never
+// blocks: a subscriber whose buffer is full misses the event.
+func (d *Dispatcher) Publish(userID uint, t Type, payload interface{}) {
+	event := Event{Type: t, Payload: payload, Time: time.Now()}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for s := range d.subs[userID] {
+		select {
+		case s.ch <- event:
+		default:
+			log.Printf("Dropped %s event for User %d: subscriber too slow", t, userID)
+		}
+	}
+}
+
+// MatchFound tells both players of a newly made game where to go.
+func (d *Dispatcher) MatchFound(game *models.Game) {
+	for _, seat := range []struct {
+		userID uint
+		color  string
+	}{{game.WhiteID, "white"}, {game.BlackID, "black"}} {
+		d.Publish(seat.userID, MatchFound, MatchFoundPayload{
+			GameID:      game.ID,
+			Color:       seat.color,
+			Mode:        game.Mode,
+			TimeControl: game.TimeControl,
+			Rated:       game.Rated,
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package game
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"time"
@@ -8,32 +9,52 @@ import (
 	"github.com/datmedevil17/chesss/internal/services/engine"
 )
 
+// botMoveTimeout bounds how long a bot waits for an engine from the pool,
+// on top of its thinking time.
+const botMoveTimeout = 30 * time.Second
+
+// Bot is an engine seated in a room like any other player. It follows the
+// game through the room's broadcasts and answers when it is its turn.
 type Bot struct {
-	Client  *Client
-	Engine  *engine.Engine
-	History []string
+	Client   *Client
+	Pool     *engine.Pool
+	Strength engine.Strength
+	StartFEN string
+	History  []string
+}
+
+// botMessage defers decoding the payload until the type is known.
+type botMessage struct {
+	Type    MessageType     `json:"type"`
+	Payload json.RawMessage `json:"payload"`
 }
 
-func NewBot(room *GameRoom, enginePath string) *Bot {
-	eng, err := engine.NewEngine(enginePath)
+// NewBot seats a bot of the given difficulty level in room as role. It
+// borrows an engine from pool for each move.
+func NewBot(room *GameRoom, pool *engine.Pool, level int, role string) *Bot {
+	strength, err := engine.LevelStrength(level)
 	if err != nil {
-		log.Printf("Failed to start engine: %v", err)
+		log.Printf("Invalid bot level %d: %v", level, err)
 		return nil
 	}
 
 	botClient := &Client{
-		Send: make(chan []byte, 256),
-		Role: "black",
+		Send:   make(chan []byte, 256),
+		Role:   role,
+		IsBot:  true,
+		UserID: room.seatUserID(role),
 	}
 
 	bot := &Bot{
-		Client:  botClient,
-		Engine:  eng,
-		History: make([]string, 0),
+		Client:   botClient,
+		Pool:     pool,
+		Strength: strength,
 	}
 
-	// Register bot to room
-	room.Register <- botClient
+	// Join the room; the init message starts the bot off
+	if !room.Join(botClient) {
+		return nil
+	}
 
 	// Start bot loop
 	go bot.Run(room)
@@ -41,106 +62,100 @@ func NewBot(room *GameRoom, enginePath string) *Bot {
 	return bot
 }
 
+// Run follows the room until it closes the bot's Send channel.
 func (b *Bot) Run(room *GameRoom) {
-	defer b.Engine.Close()
-
 	for msg := range b.Client.Send {
-		// Parse the broadcasted message
-		var wsMsg WSMessage
+		var wsMsg botMessage
 		if err := json.Unmarshal(msg, &wsMsg); err != nil {
 			log.Printf("Bot could not parse message: %v", err)
 			continue
 		}
 
-		// Only respond to Moves and Init
+		var turn string
 		switch wsMsg.Type {
 		case MsgInit:
-			// Initialize bot with existing history if any
-			if payloadMap, ok := wsMsg.Payload.(map[string]interface{}); ok {
-				if history, ok := payloadMap["history"].([]interface{}); ok {
-					for _, move := range history {
-						if moveStr, ok := move.(string); ok {
-							b.History = append(b.History, moveStr)
-						}
-					}
-				}
+			var init InitPayload
+			if err := json.Unmarshal(wsMsg.Payload, &init); err != nil {
+				continue
 			}
+			b.StartFEN = init.FEN
+			b.History = init.History
+			turn = init.CurrentTurn
 			log.Printf("Bot initialized with %d moves in history", len(b.History))
 
-			// If it's bot's turn (odd history = white just moved), make a move
-			if len(b.History)%2 != 0 {
-				b.makeMove(room)
-			}
-
 		case MsgMove:
-			// Handle move payload - could be string or MovePayload struct
-			var moveStr string
-
-			// Try to parse as MovePayload struct (map)
-			if payloadMap, ok := wsMsg.Payload.(map[string]interface{}); ok {
-				if move, ok := payloadMap["move"].(string); ok {
-					moveStr = move
-				}
-			} else if str, ok := wsMsg.Payload.(string); ok {
-				// Fallback: direct string payload
-				moveStr = str
-			}
-
-			if moveStr == "" {
-				log.Printf("Bot could not extract move from payload: %v", wsMsg.Payload)
+			var move MovePayload
+			if err := json.Unmarshal(wsMsg.Payload, &move); err != nil {
 				continue
 			}
+			b.History = append(b.History, move.Move)
+			turn = move.CurrentTurn
 
-			// Check if this move is already in our history (our own move echoed back)
-			if len(b.History) > 0 && b.History[len(b.History)-1] == moveStr {
+		case MsgTakebackAccept:
+			var takeback TakebackPayload
+			if err := json.Unmarshal(wsMsg.Payload, &takeback); err != nil {
 				continue
 			}
+			b.History = takeback.History
+			turn = takeback.CurrentTurn
+
+		case MsgTakebackRequest:
+			// Bots always let their opponent take a move back.
+			var offer OfferPayload
+			if err := json.Unmarshal(wsMsg.Payload, &offer); err == nil && offer.By != b.Client.Role {
+				room.exec(func() {
+					if room.Status == "active" && room.Clients[b.Client] {
+						room.acceptTakeback(b.Client)
+					}
+				})
+			}
+			continue
 
-			// Append move to history
-			b.History = append(b.History, moveStr)
-			log.Printf("Bot received move: %s, history length: %d", moveStr, len(b.History))
+		default:
+			continue
+		}
 
-			// Bot plays as Black (moves 2, 4, 6...)
-			// If history length is odd, it means White just moved. Bot's turn.
-			if len(b.History)%2 != 0 {
-				b.makeMove(room)
-			}
+		if turn == b.Client.Role {
+			b.makeMove(room)
 		}
 	}
 }
 
 func (b *Bot) makeMove(room *GameRoom) {
-	bestMove, err := b.Engine.GetBestMoveFromHistory(b.History, 10)
+	// The engine can take a while, so think outside the room goroutine and
+	// only hand over the result. The move comes back to us as a broadcast.
+	ctx, cancel := context.WithTimeout(context.Background(), b.Strength.MoveTime+botMoveTimeout)
+	defer cancel()
+
+	var bestMove string
+	err := b.Pool.Do(ctx, func(e *engine.Engine) error {
+		if err := e.SetStrength(b.Strength); err != nil {
+			return err
+		}
+		var err error
+		bestMove, err = e.BestMove(ctx, engine.SearchParams{
+			FEN:      b.StartFEN,
+			Moves:    b.History,
+			MoveTime: b.Strength.MoveTime,
+			GameID:   room.GameID,
+		})
+		return err
+	})
 	if err != nil {
 		log.Printf("Bot failed to find move: %v", err)
 		return
 	}
 
-	// Add our move to history
-	b.History = append(b.History, bestMove)
-	log.Printf("Bot making move: %s", bestMove)
-
-	// Update room state (clock, turn)
-	elapsed := int(time.Since(room.LastMoveTime).Seconds())
-	room.BlackTime -= elapsed
-	if room.BlackTime < 0 {
-		room.BlackTime = 0
-	}
-	room.LastMoveTime = time.Now()
-	room.CurrentTurn = "white"
-	room.MoveHistory = append(room.MoveHistory, bestMove)
-
-	// Construct proper MovePayload response
-	respMsg := WSMessage{
-		Type: MsgMove,
-		Payload: MovePayload{
-			Move:        bestMove,
-			WhiteTime:   room.WhiteTime,
-			BlackTime:   room.BlackTime,
-			LastMoveAt:  room.LastMoveTime.UnixMilli(),
-			CurrentTurn: room.CurrentTurn,
-		},
-	}
-	respBytes, _ := json.Marshal(respMsg)
-	room.Broadcast <- respBytes
+	room.exec(func() {
+		if room.Status != "active" || room.CurrentTurn != b.Client.Role || len(room.MoveHistory) != len(b.History) {
+			return
+		}
+		move, err := room.Game.Position().ParseMove(bestMove)
+		if err != nil {
+			log.Printf("Bot produced an illegal move %q: %v", bestMove, err)
+			return
+		}
+		log.Printf("Bot making move: %s", bestMove)
+		room.playMove(b.Client, move)
+	})
 }
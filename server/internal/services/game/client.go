@@ -5,8 +5,6 @@ import (
 	"log"
 	"time"
 
-	"github.com/datmedevil17/chesss/internal/database"
-	"github.com/datmedevil17/chesss/internal/models"
 	"github.com/gorilla/websocket"
 )
 
@@ -22,6 +20,10 @@ const (
 
 	// Maximum message size allowed from peer.
 	maxMessageSize = 512
+
+	// How long the room waits on a player whose send buffer is full before
+	// disconnecting them. Spectators are disconnected straight away.
+	playerSendWait = 100 * time.Millisecond
 )
 
 var (
@@ -34,11 +36,19 @@ type Client struct {
 	Send   chan []byte
 	UserID uint
 	Role   string // "white", "black", "spectator"
+	IsBot  bool   // Played by a Bot in this process rather than a socket
+
+	spectatorID uint // Spectator row recording this connection, if any
+
+	// Set by the room before it closes Send, and sent to the peer in the
+	// close frame.
+	closeCode   int
+	closeReason string
 }
 
 func (c *Client) ReadPump(room *GameRoom) {
 	defer func() {
-		room.Unregister <- c
+		room.Leave(c)
 		c.Conn.Close()
 	}()
 	c.Conn.SetReadLimit(maxMessageSize)
@@ -61,163 +71,167 @@ func (c *Client) ReadPump(room *GameRoom) {
 
 		log.Printf("Received message from User %d (%s): Type=%s Payload=%v", c.UserID, c.Role, wsMsg.Type, wsMsg.Payload)
 
-		switch wsMsg.Type {
-		case MsgMove:
-			// Block spectators
-			if c.Role == "spectator" {
-				log.Printf("Ignored move from spectator %d", c.UserID)
-				continue
-			}
+		// All game state belongs to the room goroutine, so the message is
+		// handled there. Once the room has dropped c, e.g. for reading too
+		// slowly, there is nothing left to read for.
+		dropped := false
+		if !room.exec(func() {
+			dropped = !room.Clients[c]
+			c.handleMessage(room, wsMsg)
+		}) || dropped {
+			break
+		}
+	}
+}
 
-			// Security: Enforce Turn
-			// If it's not this client's turn, ignore.
-			// Bot is also a client with Role "black".
-			if room.CurrentTurn != c.Role {
-				log.Printf("Ignored out-of-turn move from %s (current turn: %s)", c.Role, room.CurrentTurn)
-				continue
-			}
+// handleMessage acts on one message from the client. It runs on the room
+// goroutine.
+func (c *Client) handleMessage(room *GameRoom, wsMsg WSMessage) {
+	// A client the room has dropped has a closed Send channel, so nothing
+	// may answer it.
+	if !room.Clients[c] {
+		return
+	}
 
-			// First, deduct time from the player who just moved
-			elapsed := int(time.Since(room.LastMoveTime).Seconds())
-			if c.Role == "white" {
-				room.WhiteTime -= elapsed
-				if room.WhiteTime < 0 {
-					room.WhiteTime = 0
-				}
-			} else {
-				room.BlackTime -= elapsed
-				if room.BlackTime < 0 {
-					room.BlackTime = 0
-				}
-			}
-			room.LastMoveTime = time.Now()
-
-			// Update times in database
-			now := time.Now()
-			database.GetDB().Model(&models.Game{}).Where("id = ?", room.GameID).Updates(map[string]interface{}{
-				"white_time_remaining": room.WhiteTime,
-				"black_time_remaining": room.BlackTime,
-				"last_move_at":         now,
-			})
-
-			// Toggle Turn
-			if room.CurrentTurn == "white" {
-				room.CurrentTurn = "black"
-			} else {
-				room.CurrentTurn = "white"
-			}
+	switch wsMsg.Type {
+	case MsgMove:
+		// Block spectators
+		if c.Role == "spectator" {
+			log.Printf("Ignored move from spectator %d", c.UserID)
+			return
+		}
 
-			// Persist move to database
-			moveStr, _ := wsMsg.Payload.(string)
-			from := ""
-			to := ""
-			promo := ""
-			if len(moveStr) >= 4 {
-				from = moveStr[0:2]
-				to = moveStr[2:4]
-				if len(moveStr) > 4 {
-					promo = string(moveStr[4])
-				}
-			}
+		if room.Status != "active" {
+			c.SendError("Game is already over")
+			return
+		}
 
-			move := models.Move{
-				GameID:     room.GameID,
-				PlayerID:   c.UserID,
-				MoveNumber: len(room.MoveHistory) + 1,
-				FromSquare: from,
-				ToSquare:   to,
-				Promotion:  promo,
-			}
-			if err := database.GetDB().Create(&move).Error; err != nil {
-				log.Printf("Failed to save move: %v", err)
-			} else {
-				log.Printf("Saved move %d: %s (White: %ds, Black: %ds)", move.MoveNumber, moveStr, room.WhiteTime, room.BlackTime)
-			}
-			room.MoveHistory = append(room.MoveHistory, moveStr)
-
-			// Broadcast move with current times to everyone
-			moveMsg := WSMessage{
-				Type: MsgMove,
-				Payload: MovePayload{
-					Move:        moveStr,
-					WhiteTime:   room.WhiteTime,
-					BlackTime:   room.BlackTime,
-					LastMoveAt:  room.LastMoveTime.UnixMilli(),
-					CurrentTurn: room.CurrentTurn,
-				},
-			}
-			moveMsgBytes, _ := json.Marshal(moveMsg)
-			room.Broadcast <- moveMsgBytes
-			log.Printf("Broadcasted move from %s to room %s", c.Role, room.GameID)
-
-		case MsgChat:
-			// Handle Chat
-			// We need to inject the Sender name (which we don't track on Client struct yet, only Role)
-			// Let's use Role as sender for now, or "User"
-
-			// Payload in wsMsg is map[string]interface{} after unmarshal of interface{}
-			// So we might need to be careful.
-			// Actually, let's just re-broadcast the message but add timestamp/sender?
-			// The simplest way for now: expecting client to send {type: chat, payload: {text: "..."}}
-
-			// To do it properly:
-			// 1. Parse Payload to extract text.
-			// 2. Construct new ChatPayload with Server-side timestamp and Sender.
-			// 3. Marshal and broadcast.
-
-			// Simplified: Blind broadcast for now to get it working,
-			// BUT the plan said "Server adds sender/timestamp".
-			// Let's defer "Server adds sender" to the "Polish" phase if complexity is high.
-			// No, let's do it.
-
-			// Issue: wsMsg.Payload is map[string]interface{}.
-			if payloadMap, ok := wsMsg.Payload.(map[string]interface{}); ok {
-				if text, ok := payloadMap["text"].(string); ok {
-					outMsg := WSMessage{
-						Type: MsgChat,
-						Payload: ChatPayload{
-							Sender:    string(c.Role), // Use role (white/black) as sender for now
-							Text:      text,
-							Timestamp: time.Now().Format(time.RFC3339),
-						},
-					}
-					if bytes, err := json.Marshal(outMsg); err == nil {
-						room.Broadcast <- bytes
-					}
-				}
-			}
+		// Security: Enforce Turn
+		// If it's not this client's turn, ignore.
+		// Bot is also a client with Role "black".
+		if room.CurrentTurn != c.Role {
+			log.Printf("Ignored out-of-turn move from %s (current turn: %s)", c.Role, room.CurrentTurn)
+			return
+		}
 
-		case MsgGameOver:
-			// Handle game end - update database
-			if payloadMap, ok := wsMsg.Payload.(map[string]interface{}); ok {
-				result := ""
-				reason := ""
-				winner := ""
-				if r, ok := payloadMap["result"].(string); ok {
-					result = r
-				}
-				if r, ok := payloadMap["reason"].(string); ok {
-					reason = r
-				}
-				if w, ok := payloadMap["winner"].(string); ok {
-					winner = w
-				}
+		// Reject anything that isn't a legal move in the current position
+		moveStr, _ := wsMsg.Payload.(string)
+		move, err := room.Game.Position().ParseMove(moveStr)
+		if err != nil {
+			log.Printf("Rejected move %q from %s: %v", moveStr, c.Role, err)
+			c.SendError("Illegal move: " + moveStr)
+			return
+		}
 
-				now := time.Now()
-				database.GetDB().Model(&models.Game{}).Where("id = ?", room.GameID).Updates(map[string]interface{}{
-					"status":      "finished",
-					"result":      result,
-					"reason":      reason,
-					"finished_at": now,
+		room.playMove(c, move)
+
+	case MsgChat:
+		// Handle Chat
+		// We need to inject the Sender name (which we don't track on Client struct yet, only Role)
+		// Let's use Role as sender for now, or "User"
+
+		// Payload in wsMsg is map[string]interface{} after unmarshal of interface{}
+		// So we might need to be careful.
+		// Actually, let's just re-broadcast the message but add timestamp/sender?
+		// The simplest way for now: expecting client to send {type: chat, payload: {text: "..."}}
+
+		// To do it properly:
+		// 1. Parse Payload to extract text.
+		// 2. Construct new ChatPayload with Server-side timestamp and Sender.
+		// 3. Marshal and broadcast.
+
+		// Simplified: Blind broadcast for now to get it working,
+		// BUT the plan said "Server adds sender/timestamp".
+		// Let's defer "Server adds sender" to the "Polish" phase if complexity is high.
+		// No, let's do it.
+
+		// Issue: wsMsg.Payload is map[string]interface{}.
+		if payloadMap, ok := wsMsg.Payload.(map[string]interface{}); ok {
+			if text, ok := payloadMap["text"].(string); ok {
+				room.fanOutMessage(MsgChat, ChatPayload{
+					Sender:    string(c.Role), // Use role (white/black) as sender for now
+					Text:      text,
+					Timestamp: time.Now().Format(time.RFC3339),
 				})
-				log.Printf("Game %s ended: Result=%s, Reason=%s, Winner=%s", room.GameID, result, reason, winner)
+			}
+		}
+
+	case MsgGameOver:
+		// The server detects every other ending itself, so the only
+		// game_over a client may send is its own resignation.
+		reason := ""
+		if payloadMap, ok := wsMsg.Payload.(map[string]interface{}); ok {
+			reason, _ = payloadMap["reason"].(string)
+		}
+		if reason != "resign" {
+			log.Printf("Ignored client-reported game_over (%s) from %s %d", reason, c.Role, c.UserID)
+			return
+		}
+		if !c.Seated() {
+			c.SendError("Only seated players may resign")
+			return
+		}
+		if room.Status != "active" {
+			return
+		}
+		room.resign(c)
+
+	case MsgResign, MsgDrawOffer, MsgDrawAccept, MsgDrawDecline, MsgTakebackRequest, MsgTakebackAccept:
+		if !c.Seated() {
+			c.SendError("Spectators cannot do that")
+			return
+		}
+		if room.Status != "active" {
+			c.SendError("Game is already over")
+			return
+		}
+
+		switch wsMsg.Type {
+		case MsgResign:
+			room.resign(c)
+		case MsgDrawOffer:
+			room.offerDraw(c)
+		case MsgDrawAccept:
+			room.acceptDraw(c)
+		case MsgDrawDecline:
+			room.declineDraw(c)
+		case MsgTakebackRequest:
+			room.requestTakeback(c)
+		case MsgTakebackAccept:
+			room.acceptTakeback(c)
+		}
 
-				// Broadcast game_over to all clients
-				room.Broadcast <- message
+	case MsgEval:
+		// {type: eval, payload: {enabled: false}} stops the updates.
+		on := true
+		if payloadMap, ok := wsMsg.Payload.(map[string]interface{}); ok {
+			if enabled, ok := payloadMap["enabled"].(bool); ok {
+				on = enabled
 			}
+		}
+		room.followEval(c, on)
 
+	default:
+		log.Printf("Unknown message type: %s", wsMsg.Type)
+	}
+}
+
+// Seated reports whether the client is one of the two players.
+func (c *Client) Seated() bool {
+	return c.Role == "white" || c.Role == "black"
+}
+
+// SendError queues an error message for this client only.
+func (c *Client) SendError(message string) {
+	errMsg := WSMessage{
+		Type:    MsgError,
+		Payload: ErrorPayload{Message: message},
+	}
+	if bytes, err := json.Marshal(errMsg); err == nil {
+		select {
+		case c.Send <- bytes:
 		default:
-			log.Printf("Unknown message type: %s", wsMsg.Type)
+			log.Printf("Dropped error for User %d (%s): send buffer full", c.UserID, c.Role)
 		}
 	}
 }
@@ -233,8 +247,12 @@ func (c *Client) WritePump() {
 		case message, ok := <-c.Send:
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				// The hub closed the channel.
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				// The room closed the channel.
+				code := c.closeCode
+				if code == 0 {
+					code = websocket.CloseNormalClosure
+				}
+				c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, c.closeReason))
 				return
 			}
 
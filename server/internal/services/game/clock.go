@@ -0,0 +1,230 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/chess"
+)
+
+// TimeControl is a parsed models.Game.TimeControl string.
+type TimeControl struct {
+	Base      time.Duration
+	Increment time.Duration // Fischer: added to the mover's clock after every move
+	Delay     time.Duration // Bronstein: time used is refunded up to this amount
+}
+
+var DefaultTimeControl = TimeControl{Base: 10 * time.Minute}
+
+// Longest base time and increment or delay a game may use.
+const (
+	maxBaseMinutes  = 180
+	maxExtraSeconds = 180
+)
+
+// ParseTimeControl accepts "minutes+seconds". A trailing "d" on the second
+// part ("5+3d") makes it a Bronstein delay instead of a Fischer increment.
+// Minutes may be fractional, e.g. "0.5+0" for 30 second bullet.
+func ParseTimeControl(tc string) (TimeControl, error) {
+	base, extra, ok := strings.Cut(strings.TrimSpace(tc), "+")
+	if !ok {
+		extra = "0"
+	}
+
+	minutes, err := strconv.ParseFloat(base, 64)
+	if err != nil || math.IsNaN(minutes) || math.IsInf(minutes, 0) || minutes <= 0 || minutes > maxBaseMinutes {
+		return TimeControl{}, fmt.Errorf("invalid time control %q", tc)
+	}
+	parsed := TimeControl{Base: time.Duration(minutes * float64(time.Minute))}
+
+	delay := strings.HasSuffix(extra, "d")
+	seconds, err := strconv.Atoi(strings.TrimSuffix(extra, "d"))
+	if err != nil || seconds < 0 || seconds > maxExtraSeconds {
+		return TimeControl{}, fmt.Errorf("invalid time control %q", tc)
+	}
+	if delay {
+		parsed.Delay = time.Duration(seconds) * time.Second
+	} else {
+		parsed.Increment = time.Duration(seconds) * time.Second
+	}
+	return parsed, nil
+}
+
+// Mode is the rating category the time control falls in, judged by the
+// expected length of a 40-move game: "bullet", "blitz" or "rapid".
+func (tc TimeControl) Mode() string {
+	estimate := tc.Base + 40*(tc.Increment+tc.Delay)
+	switch {
+	case estimate < 3*time.Minute:
+		return "bullet"
+	case estimate < 8*time.Minute:
+		return "blitz"
+	}
+	return "rapid"
+}
+
+// Clock is a two-sided chess clock. Only the side to move ticks; when its
+// time runs out the onFlag callback fires from a timer goroutine.
+type Clock struct {
+	mu        sync.Mutex
+	control   TimeControl
+	remaining [2]time.Duration // indexed by chess.Color
+	turn      chess.Color
+	started   bool // The first move has been played
+	running   bool // A side's clock is ticking; false once flagged or stopped
+	turnStart time.Time
+	timer     *time.Timer
+	onFlag    func(chess.Color)
+}
+
+func NewClock(control TimeControl, onFlag func(chess.Color)) *Clock {
+	return &Clock{
+		control:   control,
+		remaining: [2]time.Duration{control.Base, control.Base},
+		onFlag:    onFlag,
+	}
+}
+
+// Set overrides both sides' remaining time, e.g. when restoring a game.
+func (c *Clock) Set(white, black time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remaining = [2]time.Duration{white, black}
+}
+
+// Start runs turn's clock as if its turn began at since.
+func (c *Clock) Start(turn chess.Color, since time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.turn = turn
+	c.turnStart = since
+	c.started, c.running = true, true
+	c.arm()
+}
+
+// Punch ends the current turn: the mover is charged for the time used,
+// gets their increment and the opponent's clock starts. If the mover had
+// already run out, nothing changes and flagged is true. The first punch of
+// a game only starts the clock, so nobody is charged before the first move.
+func (c *Clock) Punch(now time.Time) (flagged bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The flag timer may have fired while this move was on its way.
+	if c.remaining[c.turn] == 0 {
+		return true
+	}
+	if !c.started || !c.running {
+		c.turn = c.turn.Other()
+		c.turnStart = now
+		c.started, c.running = true, true
+		c.arm()
+		return false
+	}
+
+	used := now.Sub(c.turnStart)
+	if used >= c.remaining[c.turn] {
+		return true
+	}
+	if c.control.Delay > 0 {
+		used -= min(used, c.control.Delay)
+	}
+	c.remaining[c.turn] += c.control.Increment - used
+
+	c.turn = c.turn.Other()
+	c.turnStart = now
+	c.arm()
+	return false
+}
+
+// Stop freezes both clocks, charging the side to move for its time so far.
+func (c *Clock) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.running {
+		return
+	}
+	c.remaining[c.turn] = c.live(c.turn, time.Now())
+	c.running = false
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+}
+
+// Rewind puts the clock back to an earlier turn, as for a takeback. If
+// running, turn's clock restarts from now; otherwise it waits for the next
+// punch like a fresh clock.
+func (c *Clock) Rewind(white, black time.Duration, turn chess.Color, running bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remaining = [2]time.Duration{white, black}
+	c.turn = turn
+	c.turnStart = time.Now()
+	c.started, c.running = running, running
+	if running {
+		c.arm()
+	} else if c.timer != nil {
+		c.timer.Stop()
+	}
+}
+
+// Remaining returns the time left for color right now.
+func (c *Clock) Remaining(color chess.Color) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.live(color, time.Now())
+}
+
+// Snapshot returns both sides' time as of the start of the current turn
+// together with when that turn began, which is what clients extrapolate
+// from. Before the clock starts, turnStart is the current time.
+func (c *Clock) Snapshot() (white, black time.Duration, turnStart time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	turnStart = c.turnStart
+	if !c.running {
+		turnStart = time.Now()
+	}
+	return c.remaining[chess.White], c.remaining[chess.Black], turnStart
+}
+
+// Running reports whether a side's clock is ticking.
+func (c *Clock) Running() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.running
+}
+
+func (c *Clock) live(color chess.Color, now time.Time) time.Duration {
+	left := c.remaining[color]
+	if c.running && color == c.turn {
+		left -= now.Sub(c.turnStart)
+	}
+	return max(left, 0)
+}
+
+// arm (re)schedules the flag timer for the side to move. Callers hold mu.
+func (c *Clock) arm() {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	turn, start := c.turn, c.turnStart
+	c.timer = time.AfterFunc(c.remaining[turn]-time.Since(start), func() {
+		c.mu.Lock()
+		// A punch may have raced with the timer; only flag if the same
+		// turn is still running.
+		stale := !c.running || c.turn != turn || !c.turnStart.Equal(start)
+		if !stale {
+			c.remaining[turn] = 0
+			c.running = false
+		}
+		c.mu.Unlock()
+		if !stale && c.onFlag != nil {
+			c.onFlag(turn)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/chess"
+)
+
+func TestClockFirstPunchStartsClock(t *testing.T) {
+	c := NewClock(TimeControl{Base: time.Minute, Increment: 2 * time.Second}, nil)
+	defer c.Stop()
+
+	start := time.Now()
+	if c.Punch(start.Add(10 * time.Second)) {
+		t.Fatal("first punch flagged")
+	}
+	if !c.Running() {
+		t.Fatal("clock not running after the first move")
+	}
+	if c.Punch(start.Add(15 * time.Second)) {
+		t.Fatal("second punch flagged")
+	}
+	white, black, _ := c.Snapshot()
+	// White wasn't charged for the first move; black used 5s and got 2s back.
+	if white != time.Minute || black != 57*time.Second {
+		t.Errorf("clocks = %v %v, want 1m0s 57s", white, black)
+	}
+}
+
+func TestClockPunchAfterFlagIsRejected(t *testing.T) {
+	flagged := make(chan chess.Color)
+	release := make(chan struct{})
+	c := NewClock(TimeControl{Base: 20 * time.Millisecond}, func(color chess.Color) {
+		flagged <- color
+		<-release // The room hasn't handled the flag yet
+	})
+	defer close(release)
+
+	c.Start(chess.White, time.Now())
+	if color := <-flagged; color != chess.White {
+		t.Fatalf("flagged %v, want white", color)
+	}
+	// A move arriving between the timer firing and the room ending the
+	// game must not go through.
+	if !c.Punch(time.Now()) {
+		t.Fatal("punch after the flag fell was accepted")
+	}
+	if c.Running() {
+		t.Error("clock restarted by a late move")
+	}
+}
+
+func TestClockRewindBeforeFirstMove(t *testing.T) {
+	c := NewClock(TimeControl{Base: time.Minute}, nil)
+	c.Start(chess.Black, time.Now())
+	c.Rewind(time.Minute, time.Minute, chess.White, false)
+
+	// After a takeback to the start, the next move starts the clock again
+	// without charging anyone.
+	if c.Punch(time.Now().Add(time.Hour)) {
+		t.Fatal("punch after rewind flagged")
+	}
+	defer c.Stop()
+	white, _, _ := c.Snapshot()
+	if white != time.Minute {
+		t.Errorf("white = %v, want 1m0s", white)
+	}
+}
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		in   string
+		want TimeControl
+		mode string
+	}{
+		{"3+2", TimeControl{Base: 3 * time.Minute, Increment: 2 * time.Second}, "blitz"},
+		{"0.5+0", TimeControl{Base: 30 * time.Second}, "bullet"},
+		{"10", TimeControl{Base: 10 * time.Minute}, "rapid"},
+		{"5+3d", TimeControl{Base: 5 * time.Minute, Delay: 3 * time.Second}, "blitz"},
+		{"180+180", TimeControl{Base: 180 * time.Minute, Increment: 180 * time.Second}, "rapid"},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeControl(tt.in)
+		if err != nil {
+			t.Errorf("ParseTimeControl(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want || got.Mode() != tt.mode {
+			t.Errorf("ParseTimeControl(%q) = %+v (%s), want %+v (%s)", tt.in, got, got.Mode(), tt.want, tt.mode)
+		}
+	}
+
+	for _, in := range []string{
+		"", "+2", "abc+0", "0+5", "-3+2", "3+-1", "3+x", "3+2.5",
+		"NaN+0", "nan+0", "Inf+0", "+Inf+0", "-Inf+0", "1e30+0", "181+0", "3+181", "3+181d",
+	} {
+		if tc, err := ParseTimeControl(in); err == nil {
+			t.Errorf("ParseTimeControl(%q) = %+v, want an error", in, tc)
+		}
+	}
+}
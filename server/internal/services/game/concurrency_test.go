@@ -0,0 +1,196 @@
+package game
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/chess"
+)
+
+// drain reads c's messages until the room closes its Send channel.
+func drain(c *Client, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range c.Send {
+		}
+	}()
+}
+
+// These tests are meant for go test -race: they hammer a room from many
+// goroutines at once, the way sockets, bots and timers do in production.
+
+func TestRoomConcurrentMovesJoinsAndLeaves(t *testing.T) {
+	const (
+		maxPlies   = 60
+		spectators = 16
+		visits     = 20
+	)
+	r := NewGameRoom("hammer")
+	r.WhiteID, r.BlackID = 1, 2
+	hub := NewHub(time.Minute, nil, nil, nil)
+	hub.games[r.GameID] = r
+	go r.Run()
+
+	var drained sync.WaitGroup
+	white := newTestClient("white", 1, 256)
+	black := newTestClient("black", 2, 256)
+	for _, c := range []*Client{white, black} {
+		drain(c, &drained)
+		if !r.Join(c) {
+			t.Fatal("room closed")
+		}
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	// Both players send moves as fast as they can, from a possibly stale
+	// view of the board, so some arrive out of turn or illegal.
+	for i, player := range []*Client{white, black} {
+		wg.Add(1)
+		go func(c *Client, seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for {
+				var fen string
+				var plies int
+				var active bool
+				r.exec(func() {
+					fen, plies, active = r.Game.Position().FEN(), len(r.MoveHistory), r.Status == "active"
+				})
+				if !active || plies >= maxPlies {
+					return
+				}
+				pos, err := chess.ParseFEN(fen)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				moves := pos.LegalMoves()
+				move := moves[rng.Intn(len(moves))].String()
+				r.exec(func() { c.handleMessage(r, WSMessage{Type: MsgMove, Payload: move}) })
+			}
+		}(player, int64(i+1))
+	}
+
+	// Spectators come and go, some following the evaluation and chatting.
+	for i := 0; i < spectators; i++ {
+		wg.Add(1)
+		go func(id uint) {
+			defer wg.Done()
+			for v := 0; v < visits; v++ {
+				c := newTestClient("spectator", id, 64)
+				drain(c, &drained)
+				if !r.Join(c) {
+					return
+				}
+				r.exec(func() {
+					c.handleMessage(r, WSMessage{Type: MsgChat, Payload: map[string]interface{}{"text": "hi"}})
+				})
+				r.Leave(c)
+			}
+		}(uint(100 + i))
+	}
+
+	// A player reconnects from a second tab over and over.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for v := 0; v < visits; v++ {
+			c := newTestClient("white", 1, 256)
+			drain(c, &drained)
+			if !r.Join(c) {
+				return
+			}
+			r.Leave(c)
+		}
+	}()
+
+	// Anyone may look at the room while this goes on.
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			hub.LiveGames(SortByViewers)
+			r.Clock.Remaining(chess.White)
+		}
+	}()
+
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+
+	r.exec(func() {
+		t.Logf("%d plies, status %s", len(r.MoveHistory), r.Status)
+		replayed, err := ReplayGame(r.StartFEN, r.MoveHistory)
+		if err != nil {
+			t.Fatalf("history does not replay: %v", err)
+		}
+		if replayed.Position().FEN() != r.Game.Position().FEN() {
+			t.Errorf("board %s, history gives %s", r.Game.Position().FEN(), replayed.Position().FEN())
+		}
+		if want := r.Game.Position().Turn().String(); r.CurrentTurn != want {
+			t.Errorf("CurrentTurn = %s, want %s", r.CurrentTurn, want)
+		}
+		if len(r.Clients) != 2 || !r.Clients[white] || !r.Clients[black] {
+			t.Errorf("%d clients left, want the two players", len(r.Clients))
+		}
+		if r.seats["white"] != 1 || r.seats["black"] != 1 {
+			t.Errorf("seats = %v, want one connection each", r.seats)
+		}
+		if n := r.spectators(); n != 0 {
+			t.Errorf("%d spectators left", n)
+		}
+	})
+
+	r.Leave(white)
+	r.Leave(black)
+	drained.Wait()
+}
+
+func TestRoomJoinAfterShutdown(t *testing.T) {
+	r := NewGameRoom("closing")
+	r.Status = "finished"
+	go r.Run()
+
+	// The last client leaving a finished game shuts the room down; joins
+	// racing with that either get in first or are told to retry.
+	var drained, wg sync.WaitGroup
+	c := newTestClient("spectator", 0, 64)
+	drain(c, &drained)
+	r.Join(c)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			late := newTestClient("spectator", 0, 64)
+			drain(late, &drained)
+			if r.Join(late) {
+				r.Leave(late)
+			} else {
+				close(late.Send)
+			}
+		}()
+	}
+	r.Leave(c)
+	wg.Wait()
+
+	select {
+	case <-r.done:
+	case <-time.After(time.Second):
+		t.Fatal("room did not shut down once empty")
+	}
+	if r.exec(func() {}) {
+		t.Error("exec ran on a closed room")
+	}
+	drained.Wait()
+}
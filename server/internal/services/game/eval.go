@@ -0,0 +1,289 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/chess"
+	"github.com/datmedevil17/chesss/internal/services/engine"
+)
+
+const (
+	// Thinking time for each live evaluation.
+	evalMoveTime = time.Second
+
+	// Minimum time between two evaluations of the same game.
+	evalInterval = 3 * time.Second
+
+	// How many live evaluations run at once across all games. When more
+	// games are waiting, the one with the most viewers goes first.
+	liveEvalWorkers = 1
+
+	// How long one evaluation may wait for an engine and search, and how
+	// long a game waits after one fails.
+	evalTimeout    = 10 * time.Second
+	evalRetryDelay = 30 * time.Second
+
+	// Evaluations trail the game by this many plies, so a player watching
+	// their own game from a second connection never sees a line for the
+	// position they have to move in. For the same reason the best move and
+	// line are cut down to the moves that were actually played since.
+	evalLagPlies = 2
+
+	// Only games with at least this many spectators are evaluated, so the
+	// shared engines go to the games people are watching.
+	evalMinSpectators = 3
+)
+
+// evalJob is a request to evaluate a room's current position.
+type evalJob struct {
+	room     *GameRoom
+	startFEN string
+	moves    []string
+	fen      string   // Position after moves, to check the result still applies
+	played   []string // Moves played since fen; the most the result may reveal
+	viewers  int
+}
+
+// evaluator runs the live evaluations spectators can follow. Each room has
+// at most one job waiting; a newer position replaces it.
+type evaluator struct {
+	engines *engine.Pool
+	mu      sync.Mutex
+	pending map[*GameRoom]evalJob
+	wake    chan struct{}
+}
+
+func newEvaluator(engines *engine.Pool) *evaluator {
+	e := &evaluator{
+		engines: engines,
+		pending: make(map[*GameRoom]evalJob),
+		wake:    make(chan struct{}, 1),
+	}
+	for i := 0; i < liveEvalWorkers; i++ {
+		go e.work()
+	}
+	return e
+}
+
+// request queues job, replacing any job already waiting for its room.
+func (e *evaluator) request(job evalJob) {
+	e.mu.Lock()
+	e.pending[job.room] = job
+	e.mu.Unlock()
+
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// forget drops the waiting job of a room that has shut down.
+func (e *evaluator) forget(room *GameRoom) {
+	e.mu.Lock()
+	delete(e.pending, room)
+	e.mu.Unlock()
+}
+
+// next takes the waiting job with the most viewers.
+func (e *evaluator) next() (evalJob, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var best evalJob
+	found := false
+	for _, job := range e.pending {
+		if !found || job.viewers > best.viewers {
+			best, found = job, true
+		}
+	}
+	if found {
+		delete(e.pending, best.room)
+	}
+	return best, found
+}
+
+func (e *evaluator) work() {
+	for range e.wake {
+		for {
+			job, ok := e.next()
+			if !ok {
+				break
+			}
+			payload, err := e.evaluate(job)
+			if err != nil {
+				log.Printf("Game %s: live evaluation failed: %v", job.room.GameID, err)
+			}
+			job.room.post(func() { job.room.evalDone(job, payload, err) })
+		}
+	}
+}
+
+// evaluate searches the job's position and reports it from white's point
+// of view.
+func (e *evaluator) evaluate(job evalJob) (EvalPayload, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), evalTimeout)
+	defer cancel()
+
+	var res engine.SearchResult
+	err := e.engines.Do(ctx, func(eng *engine.Engine) error {
+		if err := eng.SetStrength(engine.Strength{SkillLevel: 20}); err != nil {
+			return err
+		}
+		var err error
+		res, err = eng.Analyze(ctx, engine.SearchParams{
+			FEN:      job.startFEN,
+			Moves:    job.moves,
+			MoveTime: evalMoveTime,
+			MultiPV:  1,
+			GameID:   "live:" + job.room.GameID,
+		})
+		return err
+	})
+	if err != nil {
+		return EvalPayload{}, err
+	}
+	return newEvalPayload(job, res), nil
+}
+
+// newEvalPayload reports res from white's point of view. The best move and
+// line only go as far as they agree with the moves played since, so they
+// never show a move the players haven't already found.
+func newEvalPayload(job evalJob, res engine.SearchResult) EvalPayload {
+	payload := EvalPayload{FEN: job.fen, Ply: len(job.moves)}
+	if len(job.played) > 0 && res.BestMove == job.played[0] {
+		payload.BestMove = res.BestMove
+	}
+	if len(res.Lines) == 0 {
+		return payload
+	}
+	info := res.Lines[0]
+	payload.Depth = info.Depth
+	payload.Score, payload.Mate = info.Score.CP, info.Score.Mate
+
+	// The engine scores for the side to move; spectators get white's view.
+	pos, err := chess.ParseFEN(job.fen)
+	if err != nil {
+		return payload
+	}
+	if pos.Turn() == chess.Black {
+		payload.Score, payload.Mate = -payload.Score, -payload.Mate
+	}
+	for i, uci := range info.PV {
+		if i >= len(job.played) || uci != job.played[i] {
+			break
+		}
+		m, err := pos.ParseMove(uci)
+		if err != nil {
+			break
+		}
+		payload.Line = append(payload.Line, pos.SAN(m))
+		pos = pos.Play(m)
+	}
+	return payload
+}
+
+// followEval subscribes a spectator to live evaluations of the game, or
+// unsubscribes them. Players never get evaluations, and spectators must be
+// signed in so a player can't simply watch anonymously. Evaluations start
+// once the game has evalMinSpectators watching.
+func (r *GameRoom) followEval(c *Client, on bool) {
+	if c.Seated() || (on && (c.UserID == r.WhiteID || c.UserID == r.BlackID)) {
+		c.SendError("Only spectators can follow the evaluation")
+		return
+	}
+	if on && c.UserID == 0 {
+		c.SendError("Sign in to follow the evaluation")
+		return
+	}
+	if !on {
+		delete(r.evalViewers, c)
+		return
+	}
+	if r.evals == nil {
+		c.SendError("Live evaluation is not available")
+		return
+	}
+	r.evalViewers[c] = true
+	if _, _, fen, ok := r.evalTarget(); ok && r.eval != nil && r.eval.FEN == fen {
+		if bytes, err := json.Marshal(WSMessage{Type: MsgEval, Payload: r.eval}); err == nil {
+			r.deliver(c, bytes)
+		}
+	}
+	r.scheduleEval()
+}
+
+// scheduleEval asks for an evaluation if anyone is following, enough people
+// are watching and the game hasn't been evaluated too recently.
+func (r *GameRoom) scheduleEval() {
+	if r.evals == nil || len(r.evalViewers) == 0 || r.Status != "active" {
+		return
+	}
+	if r.spectators() < evalMinSpectators {
+		return
+	}
+	if r.evalPending || r.evalTimer != nil {
+		return
+	}
+	moves, played, fen, ok := r.evalTarget()
+	if !ok || (r.eval != nil && r.eval.FEN == fen) {
+		return
+	}
+	if wait := time.Until(r.evalNext); wait > 0 {
+		r.evalTimer = time.AfterFunc(wait, func() {
+			r.post(func() {
+				r.evalTimer = nil
+				r.scheduleEval()
+			})
+		})
+		return
+	}
+
+	r.evalPending = true
+	r.evals.request(evalJob{
+		room:     r,
+		startFEN: r.StartFEN,
+		moves:    moves,
+		fen:      fen,
+		played:   played,
+		viewers:  r.spectators(),
+	})
+}
+
+// evalDone receives a finished evaluation on the room goroutine and sends
+// it to the spectators following it, unless the game has moved on.
+func (r *GameRoom) evalDone(job evalJob, payload EvalPayload, err error) {
+	r.evalPending = false
+	if err != nil {
+		r.evalNext = time.Now().Add(evalRetryDelay)
+	} else {
+		r.evalNext = time.Now().Add(evalInterval)
+	}
+	if _, _, fen, ok := r.evalTarget(); err == nil && ok && job.fen == fen {
+		r.eval = &payload
+		if bytes, err := json.Marshal(WSMessage{Type: MsgEval, Payload: payload}); err == nil {
+			r.fanOutTo(r.evalViewers, bytes)
+		}
+	}
+	r.scheduleEval()
+}
+
+// evalTarget returns the moves leading to the position spectators get
+// evaluated, evalLagPlies behind the game, the moves played since and that
+// position's FEN. It reports false until enough moves have been played.
+func (r *GameRoom) evalTarget() (moves, played []string, fen string, ok bool) {
+	plies := len(r.MoveHistory) - evalLagPlies
+	if plies < 0 {
+		return nil, nil, "", false
+	}
+	moves = append([]string{}, r.MoveHistory[:plies]...)
+	played = append([]string{}, r.MoveHistory[plies:]...)
+	g, err := ReplayGame(r.StartFEN, moves)
+	if err != nil {
+		return nil, nil, "", false
+	}
+	return moves, played, g.Position().FEN(), true
+}
@@ -0,0 +1,79 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/datmedevil17/chesss/internal/chess"
+	"github.com/datmedevil17/chesss/internal/services/engine"
+)
+
+func TestEvalPayloadOnlyShowsPlayedMoves(t *testing.T) {
+	// Evaluated after 1. e4 e5; 2. Nf3 Nc6 has been played since.
+	fen := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2"
+	job := evalJob{fen: fen, moves: []string{"e2e4", "e7e5"}, played: []string{"g1f3", "b8c6"}}
+
+	tests := []struct {
+		name     string
+		pv       []string
+		bestMove string
+		line     []string
+	}{
+		{"line was played", []string{"g1f3", "b8c6", "f1b5", "a7a6"}, "g1f3", []string{"Nf3", "Nc6"}},
+		{"players left the line", []string{"g1f3", "g8f6", "f3e5"}, "g1f3", []string{"Nf3"}},
+		{"players missed the best move", []string{"d2d4", "e5d4"}, "", nil},
+	}
+	for _, tt := range tests {
+		res := engine.SearchResult{
+			BestMove: tt.pv[0],
+			Lines:    []engine.Info{{Depth: 12, Score: engine.Score{CP: 40}, PV: tt.pv}},
+		}
+		got := newEvalPayload(job, res)
+		if got.BestMove != tt.bestMove || !reflect.DeepEqual(got.Line, tt.line) {
+			t.Errorf("%s: best move %q line %v, want %q %v", tt.name, got.BestMove, got.Line, tt.bestMove, tt.line)
+		}
+		if got.FEN != fen || got.Ply != 2 || got.Depth != 12 || got.Score != 40 {
+			t.Errorf("%s: payload = %+v", tt.name, got)
+		}
+	}
+}
+
+func TestEvalPayloadScoresFromWhite(t *testing.T) {
+	g, err := ReplayGame(chess.StartFEN, []string{"e2e4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := evalJob{fen: g.Position().FEN(), moves: []string{"e2e4"}, played: []string{"e7e5", "g1f3"}}
+	if g.Position().Turn() != chess.Black {
+		t.Fatal("expected black to move")
+	}
+	res := engine.SearchResult{BestMove: "e7e5", Lines: []engine.Info{{Score: engine.Score{Mate: 3}, PV: []string{"e7e5"}}}}
+	if got := newEvalPayload(job, res); got.Mate != -3 || got.Score != 0 {
+		t.Errorf("mate %d score %d, want -3 0", got.Mate, got.Score)
+	}
+}
+
+func TestEvalWaitsForSpectators(t *testing.T) {
+	room := NewGameRoom("eval")
+	room.WhiteID, room.BlackID = 1, 2
+	room.evals = &evaluator{pending: make(map[*GameRoom]evalJob), wake: make(chan struct{}, 1)}
+	room.MoveHistory = []string{"e2e4", "e7e5", "g1f3"}
+
+	follower := newTestClient("spectator", 10, 8)
+	room.addClient(follower)
+	room.followEval(follower, true)
+	if len(room.evals.pending) != 0 {
+		t.Fatal("evaluation queued with a single spectator")
+	}
+
+	for i := 1; i < evalMinSpectators; i++ {
+		room.addClient(newTestClient("spectator", uint(10+i), 8))
+	}
+	job, ok := room.evals.pending[room]
+	if !ok {
+		t.Fatalf("no evaluation queued with %d spectators", evalMinSpectators)
+	}
+	if !reflect.DeepEqual(job.moves, []string{"e2e4"}) || !reflect.DeepEqual(job.played, []string{"e7e5", "g1f3"}) {
+		t.Errorf("job moves %v played %v", job.moves, job.played)
+	}
+}
@@ -0,0 +1,165 @@
+package game
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/database"
+	"github.com/datmedevil17/chesss/internal/models"
+)
+
+const (
+	DefaultHistoryLimit = 20
+	MaxHistoryLimit     = 100
+)
+
+// ErrInvalidFilter is wrapped by the errors ListUserGames returns for bad
+// filter values.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// HistoryFilter narrows a user's game list. Empty fields don't filter.
+type HistoryFilter struct {
+	Mode       string     // bullet | blitz | rapid | ai
+	Result     string     // win | loss | draw, from the user's side
+	Color      string     // white | black, the user's side
+	OpponentID uint       // Only games against this user
+	Rated      *bool      // Rated or casual only
+	From       *time.Time // Created at or after
+	To         *time.Time // Created before
+	Cursor     string     // NextCursor of the previous page
+	Limit      int        // Page size, up to MaxHistoryLimit
+}
+
+// HistoryPage is one page of a user's games, newest first.
+type HistoryPage struct {
+	Games      []models.Game
+	MoveCounts map[string]int // Plies played per game ID
+	NextCursor string         // Empty on the last page
+}
+
+// ListUserGames returns the games userID played, newest first. Imported
+// games are left out, since the user didn't play them.
+func (s *Service) ListUserGames(userID uint, f HistoryFilter) (*HistoryPage, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	if limit > MaxHistoryLimit {
+		limit = MaxHistoryLimit
+	}
+
+	q := database.GetDB().
+		Preload("White").
+		Preload("Black").
+		Where("(white_id = ? OR black_id = ?) AND status NOT IN ?", userID, userID, []string{"imported", "waiting"})
+
+	switch f.Color {
+	case "":
+	case "white":
+		q = q.Where("white_id = ?", userID)
+	case "black":
+		q = q.Where("black_id = ?", userID)
+	default:
+		return nil, fmt.Errorf("%w: color %q", ErrInvalidFilter, f.Color)
+	}
+
+	switch f.Result {
+	case "":
+	case "win":
+		q = q.Where("(white_id = ? AND result = ?) OR (black_id = ? AND result = ?)", userID, "1-0", userID, "0-1")
+	case "loss":
+		q = q.Where("(white_id = ? AND result = ?) OR (black_id = ? AND result = ?)", userID, "0-1", userID, "1-0")
+	case "draw":
+		q = q.Where("result = ?", "1/2-1/2")
+	default:
+		return nil, fmt.Errorf("%w: result %q", ErrInvalidFilter, f.Result)
+	}
+
+	if f.Mode != "" {
+		q = q.Where("mode = ?", f.Mode)
+	}
+	if f.OpponentID != 0 {
+		q = q.Where("(white_id = ? AND black_id = ?) OR (black_id = ? AND white_id = ?)", userID, f.OpponentID, userID, f.OpponentID)
+	}
+	if f.Rated != nil {
+		q = q.Where("rated = ?", *f.Rated)
+	}
+	if f.From != nil {
+		q = q.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("created_at < ?", *f.To)
+	}
+	if f.Cursor != "" {
+		at, id, err := decodeCursor(f.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Where("(created_at < ?) OR (created_at = ? AND id < ?)", at, at, id)
+	}
+
+	var games []models.Game
+	if err := q.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&games).Error; err != nil {
+		return nil, err
+	}
+
+	page := &HistoryPage{Games: games, MoveCounts: map[string]int{}}
+	if len(games) > limit {
+		page.Games = games[:limit]
+		last := page.Games[limit-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	if err := s.countMoves(page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (s *Service) countMoves(page *HistoryPage) error {
+	if len(page.Games) == 0 {
+		return nil
+	}
+	ids := make([]string, len(page.Games))
+	for i, g := range page.Games {
+		ids[i] = g.ID
+	}
+	var counts []struct {
+		GameID string
+		Count  int
+	}
+	err := database.GetDB().Model(&models.Move{}).
+		Select("game_id, COUNT(*) AS count").
+		Where("game_id IN ?", ids).
+		Group("game_id").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+	for _, c := range counts {
+		page.MoveCounts[c.GameID] = c.Count
+	}
+	return nil
+}
+
+// A cursor is the position of the last game on a page: its creation time
+// and ID, which together order games uniquely.
+func encodeCursor(at time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(at.UnixNano(), 10) + "_" + id))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: cursor", ErrInvalidFilter)
+	}
+	nanos, id, ok := strings.Cut(string(raw), "_")
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if !ok || err != nil || id == "" {
+		return time.Time{}, "", fmt.Errorf("%w: cursor", ErrInvalidFilter)
+	}
+	return time.Unix(0, n), id, nil
+}
@@ -1,28 +1,218 @@
 package game
 
-import "sync"
+import (
+	"errors"
+	"expvar"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/services/analysis"
+	"github.com/datmedevil17/chesss/internal/services/engine"
+	"github.com/datmedevil17/chesss/internal/services/events"
+)
+
+// liveRooms counts the rooms currently running, published at /debug/vars.
+var liveRooms = expvar.NewInt("game_rooms_live")
 
 type Hub struct {
-	games map[string]*GameRoom
-	mu    sync.RWMutex
+	games          map[string]*GameRoom
+	loading        map[string]*roomLoad // Games being loaded, so each loads once
+	load           func(gameID string) (*GameRoom, error)
+	mu             sync.RWMutex
+	reconnectGrace time.Duration
+	engines        *engine.Pool
+	analysis       *analysis.Service
+	evals          *evaluator
+	events         *events.Dispatcher
 }
 
-func NewHub() *Hub {
+func NewHub(reconnectGrace time.Duration, engines *engine.Pool, analyzer *analysis.Service, dispatcher *events.Dispatcher) *Hub {
 	return &Hub{
-		games: make(map[string]*GameRoom),
+		games:          make(map[string]*GameRoom),
+		loading:        make(map[string]*roomLoad),
+		load:           LoadGameRoom,
+		reconnectGrace: reconnectGrace,
+		engines:        engines,
+		analysis:       analyzer,
+		evals:          newEvaluator(engines),
+		events:         dispatcher,
 	}
 }
 
-func (h *Hub) GetRoom(gameID string) *GameRoom {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+// roomLoad is a room being loaded from the database. Callers asking for the
+// same game wait on done and share the outcome.
+type roomLoad struct {
+	done chan struct{}
+	room *GameRoom
+	err  error
+}
 
+// GetRoom returns the live room for a game, loading it from the database
+// the first time it is asked for. The load runs without holding mu, so a
+// slow one only holds up callers asking for the same game.
+func (h *Hub) GetRoom(gameID string) (*GameRoom, error) {
+	h.mu.Lock()
 	if room, ok := h.games[gameID]; ok {
-		return room
+		h.mu.Unlock()
+		return room, nil
+	}
+	if load, ok := h.loading[gameID]; ok {
+		h.mu.Unlock()
+		<-load.done
+		return load.room, load.err
 	}
+	load := &roomLoad{done: make(chan struct{})}
+	h.loading[gameID] = load
+	h.mu.Unlock()
 
-	room := NewGameRoom(gameID)
+	load.room, load.err = h.startRoom(gameID)
+	close(load.done)
+	return load.room, load.err
+}
+
+// startRoom loads a game's room, registers it and starts it running.
+func (h *Hub) startRoom(gameID string) (*GameRoom, error) {
+	room, err := h.load(gameID)
+	if err != nil {
+		h.mu.Lock()
+		delete(h.loading, gameID)
+		h.mu.Unlock()
+		return nil, err
+	}
+	room.ReconnectGrace = h.reconnectGrace
+	room.evals = h.evals
+	room.events = h.events
+	room.onClose = func() { h.removeRoom(room) }
+	room.onFinish = func() { go h.requestAnalysis(gameID) }
+
+	h.mu.Lock()
+	delete(h.loading, gameID)
 	h.games[gameID] = room
+	h.mu.Unlock()
+	liveRooms.Add(1)
 	go room.Run()
-	return room
+
+	// The room owns its bot, so there is exactly one however often the
+	// player reconnects.
+	if room.BotRole != "" && room.Status == "active" {
+		if NewBot(room, h.engines, room.Difficulty, room.BotRole) == nil {
+			log.Printf("Game %s: could not start bot", gameID)
+		}
+	}
+	return room, nil
+}
+
+// JoinRoom adds c to the game's room, loading a new room if the one it
+// found shut down in the meantime.
+func (h *Hub) JoinRoom(gameID string, c *Client) (*GameRoom, error) {
+	for {
+		room, err := h.GetRoom(gameID)
+		if err != nil {
+			return nil, err
+		}
+		if room.Join(c) {
+			return room, nil
+		}
+	}
+}
+
+// removeRoom forgets a room that has shut down. A newer room for the same
+// game is left alone.
+func (h *Hub) removeRoom(room *GameRoom) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.games[room.GameID] == room {
+		delete(h.games, room.GameID)
+	}
+	liveRooms.Add(-1)
+}
+
+// requestAnalysis queues computer analysis of a game that just finished.
+func (h *Hub) requestAnalysis(gameID string) {
+	if h.analysis == nil {
+		return
+	}
+	if _, err := h.analysis.Request(gameID); err != nil && !errors.Is(err, analysis.ErrNoMoves) {
+		log.Printf("Failed to queue analysis of game %s: %v", gameID, err)
+	}
+}
+
+// LiveGame is a snapshot of an active game running in a room.
+type LiveGame struct {
+	GameID      string
+	WhiteName   string
+	BlackName   string
+	WhiteRating int
+	BlackRating int
+	Mode        string
+	TimeControl string
+	Rated       bool
+	FEN         string // Current position
+	Plies       int
+	Spectators  int
+}
+
+// AverageRating is the mean of both players' ratings.
+func (g LiveGame) AverageRating() int {
+	return (g.WhiteRating + g.BlackRating) / 2
+}
+
+// Orders for LiveGames.
+const (
+	SortByRating  = "rating"
+	SortByViewers = "viewers"
+)
+
+// LiveGames returns the active games being played in this process, sorted
+// by SortByRating or SortByViewers, highest first.
+func (h *Hub) LiveGames(sortBy string) []LiveGame {
+	h.mu.RLock()
+	rooms := make([]*GameRoom, 0, len(h.games))
+	for _, room := range h.games {
+		rooms = append(rooms, room)
+	}
+	h.mu.RUnlock()
+
+	games := make([]LiveGame, 0, len(rooms))
+	for _, room := range rooms {
+		var g LiveGame
+		active := false
+		room.exec(func() {
+			if room.Status != "active" {
+				return
+			}
+			active = true
+			g = LiveGame{
+				GameID:      room.GameID,
+				WhiteName:   room.WhiteName,
+				BlackName:   room.BlackName,
+				WhiteRating: room.WhiteRating,
+				BlackRating: room.BlackRating,
+				Mode:        room.Mode,
+				TimeControl: room.TimeControl,
+				Rated:       room.Rated,
+				FEN:         room.Game.Position().FEN(),
+				Plies:       len(room.MoveHistory),
+				Spectators:  room.spectators(),
+			}
+		})
+		if active {
+			games = append(games, g)
+		}
+	}
+
+	sort.Slice(games, func(i, j int) bool {
+		a, b := games[i], games[j]
+		if sortBy == SortByViewers && a.Spectators != b.Spectators {
+			return a.Spectators > b.Spectators
+		}
+		if a.AverageRating() != b.AverageRating() {
+			return a.AverageRating() > b.AverageRating()
+		}
+		return a.GameID < b.GameID
+	})
+	return games
 }
@@ -0,0 +1,79 @@
+package game
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHubSlowLoadHoldsUpOnlyItsGame(t *testing.T) {
+	hub := NewHub(time.Minute, nil, nil, nil)
+	release := make(chan struct{})
+	var slowLoads atomic.Int32
+	hub.load = func(gameID string) (*GameRoom, error) {
+		if gameID == "slow" {
+			slowLoads.Add(1)
+			<-release
+		}
+		return NewGameRoom(gameID), nil
+	}
+
+	// Several connections to the slow game wait on a single load.
+	var wg sync.WaitGroup
+	rooms := make([]*GameRoom, 4)
+	for i := range rooms {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			room, err := hub.GetRoom("slow")
+			if err != nil {
+				t.Errorf("GetRoom(slow): %v", err)
+			}
+			rooms[i] = room
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := hub.GetRoom("fast"); err != nil {
+			t.Errorf("GetRoom(fast): %v", err)
+		}
+		hub.LiveGames(SortByViewers)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("another game waited for the slow load")
+	}
+
+	close(release)
+	wg.Wait()
+	if n := slowLoads.Load(); n != 1 {
+		t.Errorf("slow game loaded %d times, want 1", n)
+	}
+	for _, room := range rooms {
+		if room == nil || room != rooms[0] {
+			t.Fatal("callers got different rooms for one game")
+		}
+	}
+	if len(hub.loading) != 0 {
+		t.Errorf("%d loads left behind", len(hub.loading))
+	}
+}
+
+func TestHubFailedLoadIsRetried(t *testing.T) {
+	hub := NewHub(time.Minute, nil, nil, nil)
+	hub.load = func(string) (*GameRoom, error) { return nil, errNoDB }
+	if _, err := hub.GetRoom("missing"); err != errNoDB {
+		t.Fatalf("GetRoom error = %v, want %v", err, errNoDB)
+	}
+	if len(hub.loading) != 0 || len(hub.games) != 0 {
+		t.Fatal("failed load was remembered")
+	}
+	hub.load = func(gameID string) (*GameRoom, error) { return NewGameRoom(gameID), nil }
+	if room, err := hub.GetRoom("missing"); err != nil || room == nil {
+		t.Fatalf("GetRoom after a failed load = %v, %v", room, err)
+	}
+}
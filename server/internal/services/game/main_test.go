@@ -0,0 +1,60 @@
+package game
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/database"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// noDB is a connection that refuses every query. Rooms save moves and
+// spectators as they go; in tests those writes are built but never run.
+type noDB struct{}
+
+var errNoDB = errors.New("no database in tests")
+
+func (noDB) PrepareContext(context.Context, string) (*sql.Stmt, error) { return nil, errNoDB }
+func (noDB) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errNoDB
+}
+func (noDB) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errNoDB
+}
+func (noDB) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	panic("game tests: QueryRowContext without a database")
+}
+
+func TestMain(m *testing.M) {
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: noDB{}}), &gorm.Config{DryRun: true})
+	if err != nil {
+		panic(err)
+	}
+	database.DB = db
+	os.Exit(m.Run())
+}
+
+// newTestClient returns a socketless client with room for size messages.
+func newTestClient(role string, userID uint, size int) *Client {
+	return &Client{Send: make(chan []byte, size), Role: role, UserID: userID}
+}
+
+// receive waits for c's next message, failing the test if none comes.
+func receive(t *testing.T, c *Client) []byte {
+	t.Helper()
+	select {
+	case msg, ok := <-c.Send:
+		if !ok {
+			t.Fatalf("%s's connection was closed", c.Role)
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatalf("no message for %s", c.Role)
+	}
+	return nil
+}
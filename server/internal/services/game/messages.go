@@ -8,6 +8,26 @@ const (
 	MsgChat     MessageType = "chat"
 	MsgError    MessageType = "error"
 	MsgGameOver MessageType = "game_over"
+
+	MsgResign          MessageType = "resign"
+	MsgDrawOffer       MessageType = "draw_offer"
+	MsgDrawAccept      MessageType = "draw_accept"
+	MsgDrawDecline     MessageType = "draw_decline"
+	MsgTakebackRequest MessageType = "takeback_request"
+	MsgTakebackAccept  MessageType = "takeback_accept"
+
+	MsgOpponentDisconnected MessageType = "opponent_disconnected"
+	MsgOpponentReconnected  MessageType = "opponent_reconnected"
+
+	// Sent by a spectator to start or stop following the engine's
+	// evaluation, and by the server with each new evaluation.
+	MsgEval MessageType = "eval"
+
+	// Sent by the server when the number of spectators changes.
+	MsgSpectators MessageType = "spectators"
+
+	// Sent on the TV channel before each game it switches to.
+	MsgFeatured MessageType = "featured"
 )
 
 type WSMessage struct {
@@ -16,8 +36,10 @@ type WSMessage struct {
 }
 
 type InitPayload struct {
-	FEN         string   `json:"fen"`
+	FEN         string   `json:"fen"`         // Starting position; History is played from here
+	CurrentFEN  string   `json:"current_fen"` // Position after the last move
 	History     []string `json:"history"`
+	SANHistory  []string `json:"san_history"`
 	WhiteID     uint     `json:"white_id"`
 	BlackID     uint     `json:"black_id"`
 	WhiteName   string   `json:"white_name"` // Username of white player
@@ -26,8 +48,11 @@ type InitPayload struct {
 	Color       string   `json:"color"`      // Color of the connected client ("white", "black", or "spectator")
 	WhiteTime   int      `json:"white_time"` // Frozen time at last move (seconds)
 	BlackTime   int      `json:"black_time"`
+	WhiteTimeMs int64    `json:"white_time_ms"` // Same as WhiteTime with millisecond precision
+	BlackTimeMs int64    `json:"black_time_ms"`
 	LastMoveAt  int64    `json:"last_move_at"` // Unix timestamp (ms) when last move was made
 	CurrentTurn string   `json:"current_turn"` // "white" or "black"
+	Spectators  int      `json:"spectators"`   // Number of people watching
 }
 
 type ChatPayload struct {
@@ -36,16 +61,79 @@ type ChatPayload struct {
 	Timestamp string `json:"timestamp"` // ISO string
 }
 
+type ErrorPayload struct {
+	Message string `json:"message"`
+}
+
 type GameOverPayload struct {
-	Result string `json:"result"` // "1-0", "0-1", "1/2-1/2"
-	Reason string `json:"reason"` // "checkmate", "stalemate", "draw", "timeout", "resign"
+	Result string `json:"result"` // "1-0", "0-1", "1/2-1/2", or "*" if aborted
+	Reason string `json:"reason"` // "checkmate", "stalemate", "insufficient_material", "threefold_repetition", "fifty_move_rule", "timeout", "resign", "agreement", "abandonment", "aborted"
 	Winner string `json:"winner"` // "white", "black", "" (for draw)
 }
 
 type MovePayload struct {
 	Move        string `json:"move"`       // UCI move string
+	SAN         string `json:"san"`        // Standard Algebraic Notation, e.g. "Nf3+"
+	FEN         string `json:"fen"`        // Position after the move
 	WhiteTime   int    `json:"white_time"` // Frozen time at this move (seconds)
 	BlackTime   int    `json:"black_time"`
+	WhiteTimeMs int64  `json:"white_time_ms"` // Same as WhiteTime with millisecond precision
+	BlackTimeMs int64  `json:"black_time_ms"`
 	LastMoveAt  int64  `json:"last_move_at"` // Unix timestamp (ms) when this move was made
 	CurrentTurn string `json:"current_turn"` // Whose turn it is now
 }
+
+// OfferPayload is broadcast for draw offers and takeback requests and for
+// a declined draw. By is the color that made the offer or declined it.
+type OfferPayload struct {
+	By string `json:"by"` // "white" or "black"
+}
+
+// TakebackPayload is broadcast once a takeback is accepted. It carries the
+// whole rolled-back state so clients can reset their board.
+type TakebackPayload struct {
+	Plies       int      `json:"plies"` // Number of half-moves taken back
+	FEN         string   `json:"fen"`   // Position after the remaining moves
+	History     []string `json:"history"`
+	SANHistory  []string `json:"san_history"`
+	WhiteTimeMs int64    `json:"white_time_ms"`
+	BlackTimeMs int64    `json:"black_time_ms"`
+	LastMoveAt  int64    `json:"last_move_at"`
+	CurrentTurn string   `json:"current_turn"`
+}
+
+// PresencePayload reports a seated player leaving or coming back.
+type PresencePayload struct {
+	Color   string `json:"color"`    // Seat of the player who left or returned
+	GraceMs int64  `json:"grace_ms"` // On disconnect, how long they have to return
+}
+
+// EvalPayload is the engine's view of the game, sent only to signed-in
+// spectators following the evaluation of a well-watched game. It describes
+// the position a couple of plies back rather than the current one, and the
+// best move and line stop where the players left them. Scores are from
+// white's side.
+type EvalPayload struct {
+	FEN      string   `json:"fen"` // Position evaluated
+	Ply      int      `json:"ply"` // Number of moves played before it
+	Depth    int      `json:"depth"`
+	Score    int      `json:"score"`          // Centipawns; 0 when Mate is set
+	Mate     int      `json:"mate,omitempty"` // Moves to mate, negative when black mates
+	BestMove string   `json:"best_move"`      // UCI; empty unless it was played
+	Line     []string `json:"line"`           // Best line in SAN, as far as it was played
+}
+
+// SpectatorsPayload is the number of people watching the game.
+type SpectatorsPayload struct {
+	Count int `json:"count"`
+}
+
+// FeaturedPayload names the game TV is about to show; its init follows.
+// An empty GameID means no game is being played right now.
+type FeaturedPayload struct {
+	GameID      string `json:"game_id"`
+	WhiteRating int    `json:"white_rating"`
+	BlackRating int    `json:"black_rating"`
+	Mode        string `json:"mode"`
+	TimeControl string `json:"time_control"`
+}
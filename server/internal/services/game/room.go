@@ -1,46 +1,876 @@
 package game
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
 
+	"github.com/datmedevil17/chesss/internal/chess"
+	"github.com/datmedevil17/chesss/internal/database"
+	"github.com/datmedevil17/chesss/internal/models"
+	"github.com/datmedevil17/chesss/internal/services/events"
+	"github.com/datmedevil17/chesss/internal/services/rating"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// A room with nobody in it, or whose game is over, shuts down after
+	// this long without activity.
+	roomIdleTimeout = 10 * time.Minute
+
+	// How often Run checks for idleness.
+	idleCheckPeriod = time.Minute
+)
+
+// GameRoom is the live state of one game. Everything below the channels is
+// owned by the Run goroutine: other goroutines change it only through
+// commands (see exec and post), never directly.
 type GameRoom struct {
-	GameID       string
-	Register     chan *Client
-	Unregister   chan *Client
-	Broadcast    chan []byte
-	Clients      map[*Client]bool
-	CurrentTurn  string   // "white" or "black"
-	MoveHistory  []string // Track moves in memory (UCI format)
-	WhiteTime    int      // Remaining time in seconds
-	BlackTime    int
-	LastMoveTime time.Time // When last move was made
+	GameID     string
+	Register   chan *Client
+	Unregister chan *Client
+	Broadcast  chan []byte
+	commands   chan func()
+	done       chan struct{} // Closed when Run returns
+	onClose    func()        // Called by Run just before it returns
+	onFinish   func()        // Called when this room finishes the game
+	events     *events.Dispatcher
+
+	// Players and starting position, fixed once the room is loaded.
+	WhiteID     uint
+	BlackID     uint
+	WhiteName   string
+	BlackName   string
+	WhiteRating int // Rating in Mode when the room was loaded; 0 if unrated
+	BlackRating int
+	StartFEN    string
+	Mode        string
+	TimeControl string
+	Rated       bool
+
+	// Set for games against the engine: the AIGame row and the bot's seat.
+	AIGameID   string
+	BotRole    string
+	Difficulty int
+
+	Clients     map[*Client]bool
+	CurrentTurn string      // "white" or "black"
+	MoveHistory []string    // Track moves in memory (UCI format)
+	Game        *chess.Game // Positions after MoveHistory, used to validate moves and detect the end
+	Status      string      // "active", "finished", "aborted" or "imported"; moves are only accepted while active
+	Clock       *Clock
+
+	// How long a seated player may be gone before the game is decided
+	// without them; capped by their remaining clock.
+	ReconnectGrace time.Duration
+
+	clockLog        [][2]time.Duration // Both clocks at the start of each ply, for takebacks
+	drawOffer       string             // Color with a pending draw offer, if any
+	takebackRequest string             // Color with a pending takeback request, if any
+
+	seats       map[string]int         // Open connections per seat color
+	graceTimers map[string]*time.Timer // Pending abandonment per absent seat color
+	lastActive  time.Time
+
+	// Live evaluation for spectators who asked for it; see eval.go.
+	evals       *evaluator
+	evalViewers map[*Client]bool
+	eval        *EvalPayload // Latest evaluation sent
+	evalPending bool         // An evaluation is queued or running
+	evalTimer   *time.Timer  // Holds back the next evaluation until evalNext
+	evalNext    time.Time
+
+	// Spectator count updates are batched; see spectators.go.
+	spectatorsSent  int
+	spectatorsTimer *time.Timer
 }
 
 func NewGameRoom(gameID string) *GameRoom {
-	return &GameRoom{
-		GameID:       gameID,
-		Register:     make(chan *Client),
-		Unregister:   make(chan *Client),
-		Broadcast:    make(chan []byte),
-		Clients:      make(map[*Client]bool),
-		CurrentTurn:  "white",
-		MoveHistory:  []string{},
-		WhiteTime:    600, // Default 10 minutes
-		BlackTime:    600,
-		LastMoveTime: time.Now(),
+	room := &GameRoom{
+		GameID:      gameID,
+		Register:    make(chan *Client),
+		Unregister:  make(chan *Client),
+		Broadcast:   make(chan []byte),
+		commands:    make(chan func()),
+		done:        make(chan struct{}),
+		StartFEN:    chess.StartFEN,
+		Clients:     make(map[*Client]bool),
+		CurrentTurn: "white",
+		MoveHistory: []string{},
+		Game:        chess.NewGame(chess.StartingPosition()),
+		Status:      "active",
+		seats:       make(map[string]int),
+		graceTimers: make(map[string]*time.Timer),
+		evalViewers: make(map[*Client]bool),
 	}
+	room.Clock = NewClock(DefaultTimeControl, room.onFlag)
+	return room
 }
 
+// LoadGameRoom builds the room for a stored game: players, moves, status
+// and clocks all come from the database, once, when the room is created.
+func LoadGameRoom(gameID string) (*GameRoom, error) {
+	var g models.Game
+	if err := database.GetDB().Preload("White").Preload("Black").Where("id = ?", gameID).First(&g).Error; err != nil {
+		return nil, err
+	}
+	var moves []models.Move
+	if err := database.GetDB().Where("game_id = ?", gameID).Order("move_number ASC").Find(&moves).Error; err != nil {
+		return nil, err
+	}
+	history := make([]string, len(moves))
+	for i, m := range moves {
+		history[i] = m.FromSquare + m.ToSquare + m.Promotion
+	}
+
+	room := NewGameRoom(gameID)
+	room.WhiteID, room.BlackID = g.WhiteID, g.BlackID
+	room.WhiteName, room.BlackName = g.White.Username, g.Black.Username
+	if g.Status == "imported" {
+		room.WhiteName, room.BlackName = g.WhiteName, g.BlackName
+	}
+	if g.InitialFEN != "" {
+		room.StartFEN = g.InitialFEN
+	}
+	room.Mode, room.TimeControl, room.Rated = g.Mode, g.TimeControl, g.Rated
+	room.WhiteRating, room.BlackRating = modeRating(g.WhiteID, g.Mode), modeRating(g.BlackID, g.Mode)
+
+	board, err := ReplayGame(room.StartFEN, history)
+	if err != nil {
+		return nil, fmt.Errorf("replay game %s: %w", gameID, err)
+	}
+	room.Game = board
+	room.MoveHistory = history
+	room.CurrentTurn = board.Position().Turn().String()
+	room.Status = g.Status
+	room.restoreClock(&g, moves)
+
+	if g.Mode == "ai" {
+		var ai models.AIGame
+		if err := database.GetDB().Where("game_id = ?", gameID).First(&ai).Error; err != nil {
+			return nil, fmt.Errorf("load ai game %s: %w", gameID, err)
+		}
+		room.AIGameID = ai.ID
+		room.Difficulty = ai.Difficulty
+		room.BotRole = "black"
+		if ai.Color == "black" {
+			room.BotRole = "white"
+		}
+	}
+	return room, nil
+}
+
+// modeRating returns the user's rating in mode, or 0 if they have none.
+func modeRating(userID uint, mode string) int {
+	var r models.Rating
+	if err := database.GetDB().Where("user_id = ? AND mode = ?", userID, mode).First(&r).Error; err != nil {
+		return 0
+	}
+	return r.Value
+}
+
+func (r *GameRoom) seatUserID(role string) uint {
+	if role == "black" {
+		return r.BlackID
+	}
+	return r.WhiteID
+}
+
+// RoleFor returns the seat userID plays in this game, or "spectator".
+func (r *GameRoom) RoleFor(userID uint) string {
+	switch {
+	case userID == 0:
+		return "spectator"
+	case userID == r.WhiteID:
+		return "white"
+	case userID == r.BlackID:
+		return "black"
+	}
+	return "spectator"
+}
+
+// Run processes the room's events until the room shuts down: as soon as
+// the game is over and everyone has left, or after roomIdleTimeout with
+// nobody in the room.
 func (r *GameRoom) Run() {
+	ticker := time.NewTicker(idleCheckPeriod)
+	defer ticker.Stop()
+	r.lastActive = time.Now()
+
 	for {
 		select {
 		case c := <-r.Register:
-			r.Clients[c] = true
+			r.addClient(c)
 		case c := <-r.Unregister:
-			delete(r.Clients, c)
+			if _, ok := r.Clients[c]; !ok {
+				continue
+			}
+			r.removeClient(c)
 		case msg := <-r.Broadcast:
-			for c := range r.Clients {
-				c.Send <- msg
+			r.fanOut(msg)
+		case cmd := <-r.commands:
+			cmd()
+		case <-ticker.C:
+			idle := time.Since(r.lastActive) >= roomIdleTimeout
+			if idle && (r.humans() == 0 || r.Status != "active") {
+				log.Printf("Game %s: closing idle room", r.GameID)
+				r.shutdown()
+				return
 			}
+			continue
+		}
+
+		r.lastActive = time.Now()
+		if r.humans() == 0 && r.Status != "active" {
+			r.shutdown()
+			return
+		}
+	}
+}
+
+// humans counts the clients that aren't bots.
+func (r *GameRoom) humans() int {
+	n := 0
+	for c := range r.Clients {
+		if !c.IsBot {
+			n++
+		}
+	}
+	return n
+}
+
+// shutdown stops the room's timers, disconnects any remaining clients and
+// marks the room closed. Commands sent afterwards are dropped.
+func (r *GameRoom) shutdown() {
+	r.Clock.Stop()
+	for color, timer := range r.graceTimers {
+		timer.Stop()
+		delete(r.graceTimers, color)
+	}
+	if r.evalTimer != nil {
+		r.evalTimer.Stop()
+	}
+	if r.evals != nil {
+		r.evals.forget(r)
+	}
+	if r.spectatorsTimer != nil {
+		r.spectatorsTimer.Stop()
+	}
+	for c := range r.Clients {
+		if !c.Seated() {
+			r.spectatorLeft(c)
+		}
+		c.closeCode, c.closeReason = websocket.CloseGoingAway, "Game room closed"
+		delete(r.Clients, c)
+		close(c.Send)
+	}
+	if r.onClose != nil {
+		r.onClose()
+	}
+	close(r.done)
+}
+
+// exec runs fn on the room goroutine and waits for it to finish. fn may
+// touch any room state but must not call exec itself. It reports false,
+// without running fn, if the room has shut down.
+func (r *GameRoom) exec(fn func()) bool {
+	done := make(chan struct{})
+	cmd := func() {
+		fn()
+		close(done)
+	}
+	select {
+	case r.commands <- cmd:
+	case <-r.done:
+		return false
+	}
+	<-done
+	return true
+}
+
+// post queues fn to run on the room goroutine without waiting, for timer
+// callbacks that must not block.
+func (r *GameRoom) post(fn func()) {
+	go func() {
+		select {
+		case r.commands <- fn:
+		case <-r.done:
+		}
+	}()
+}
+
+// Leave removes c from the room. It is safe to call after shutdown.
+func (r *GameRoom) Leave(c *Client) {
+	select {
+	case r.Unregister <- c:
+	case <-r.done:
+	}
+}
+
+// Join sends c the current state of the game and adds it to the room in
+// one step, so no move can slip in between. It reports false if the room
+// has already shut down; the caller should get a fresh one from the Hub.
+func (r *GameRoom) Join(c *Client) bool {
+	return r.exec(func() {
+		if bytes, err := json.Marshal(WSMessage{Type: MsgInit, Payload: r.initPayload(c.Role)}); err == nil {
+			c.Send <- bytes
+		}
+		r.addClient(c)
+	})
+}
+
+func (r *GameRoom) initPayload(color string) InitPayload {
+	white, black, turnStart := r.Clock.Snapshot()
+	return InitPayload{
+		FEN:         r.StartFEN,
+		CurrentFEN:  r.Game.Position().FEN(),
+		History:     append([]string{}, r.MoveHistory...),
+		SANHistory:  r.Game.SANHistory(),
+		WhiteID:     r.WhiteID,
+		BlackID:     r.BlackID,
+		WhiteName:   r.WhiteName,
+		BlackName:   r.BlackName,
+		Status:      r.Status,
+		Color:       color,
+		WhiteTime:   int(white.Seconds()),
+		BlackTime:   int(black.Seconds()),
+		WhiteTimeMs: white.Milliseconds(),
+		BlackTimeMs: black.Milliseconds(),
+		LastMoveAt:  turnStart.UnixMilli(),
+		CurrentTurn: r.CurrentTurn,
+		Spectators:  r.spectators(),
+	}
+}
+
+// addClient adds c to the room, as a player or a spectator.
+func (r *GameRoom) addClient(c *Client) {
+	r.Clients[c] = true
+	if c.Seated() {
+		r.seatJoined(c.Role)
+	} else {
+		r.spectatorJoined(c)
+		r.announceSpectators()
+		r.scheduleEval()
+	}
+}
+
+// removeClient drops c from the room and closes its Send channel, which
+// makes its WritePump close the connection.
+func (r *GameRoom) removeClient(c *Client) {
+	delete(r.Clients, c)
+	delete(r.evalViewers, c)
+	close(c.Send)
+	if c.Seated() {
+		r.seatLeft(c.Role)
+	} else {
+		r.spectatorLeft(c)
+		r.announceSpectators()
+	}
+}
+
+// fanOut delivers msg to every client without letting one slow reader hold
+// up the room. Only the room goroutine calls it; everyone else goes through
+// the Broadcast channel.
+func (r *GameRoom) fanOut(msg []byte) {
+	r.fanOutTo(r.Clients, msg)
+}
+
+// fanOutTo is fanOut for a subset of the room's clients.
+func (r *GameRoom) fanOutTo(clients map[*Client]bool, msg []byte) {
+	for c := range clients {
+		if !r.deliver(c, msg) {
+			log.Printf("Game %s: disconnecting slow %s (User %d)", r.GameID, c.Role, c.UserID)
+			c.closeCode, c.closeReason = websocket.CloseTryAgainLater, "Connection too slow"
+			r.removeClient(c)
+		}
+	}
+}
+
+// deliver queues msg for c. A spectator with a full buffer is given up on
+// at once; a player gets playerSendWait to catch up, since losing them
+// matters more.
+func (r *GameRoom) deliver(c *Client, msg []byte) bool {
+	select {
+	case c.Send <- msg:
+		return true
+	default:
+	}
+	if !c.Seated() {
+		return false
+	}
+
+	timer := time.NewTimer(playerSendWait)
+	defer timer.Stop()
+	select {
+	case c.Send <- msg:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (r *GameRoom) fanOutMessage(t MessageType, payload interface{}) {
+	if bytes, err := json.Marshal(WSMessage{Type: t, Payload: payload}); err == nil {
+		r.fanOut(bytes)
+	}
+}
+
+// seatJoined notes a connection for color and, if that player was away,
+// cancels the abandonment timer and tells the room they are back.
+func (r *GameRoom) seatJoined(color string) {
+	r.seats[color]++
+	timer, wasAway := r.graceTimers[color]
+	if wasAway {
+		timer.Stop()
+		delete(r.graceTimers, color)
+	}
+
+	if wasAway {
+		log.Printf("Game %s: %s reconnected", r.GameID, color)
+		r.fanOutMessage(MsgOpponentReconnected, PresencePayload{Color: color})
+	}
+}
+
+// seatLeft notes a closed connection for color. When the player's last
+// connection goes during a live game, they get a grace period to return.
+func (r *GameRoom) seatLeft(color string) {
+	if r.Status != "active" {
+		return
+	}
+
+	r.seats[color]--
+	if r.seats[color] > 0 {
+		return
+	}
+	grace := r.ReconnectGrace
+	if left := r.Clock.Remaining(roleColor(color)); left < grace {
+		grace = left
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(grace, func() {
+		r.post(func() { r.graceExpired(color, timer) })
+	})
+	r.graceTimers[color] = timer
+
+	log.Printf("Game %s: %s disconnected, %v to reconnect", r.GameID, color, grace)
+	r.fanOutMessage(MsgOpponentDisconnected, PresencePayload{Color: color, GraceMs: grace.Milliseconds()})
+}
+
+// graceExpired decides the game against color if it is still away.
+func (r *GameRoom) graceExpired(color string, timer *time.Timer) {
+	if r.graceTimers[color] != timer {
+		return
+	}
+	delete(r.graceTimers, color)
+
+	r.abandon(color)
+}
+
+// abandon ends a game whose player at color left. Games that barely
+// started are aborted instead of scored.
+func (r *GameRoom) abandon(color string) {
+	if r.Status != "active" {
+		return
+	}
+	if len(r.MoveHistory) < 2 {
+		r.endGame("aborted", chess.ResultOngoing, "aborted", "")
+		return
+	}
+	winner := roleColor(color).Other()
+	r.finishGame(chess.WinResult(winner), "abandonment", winner.String())
+}
+
+// ReplayGame rebuilds a game from the starting FEN and the UCI move history
+// stored for it.
+func ReplayGame(startFEN string, history []string) (*chess.Game, error) {
+	pos, err := chess.ParseFEN(startFEN)
+	if err != nil {
+		return nil, err
+	}
+	g := chess.NewGame(pos)
+	for _, uci := range history {
+		if _, err := g.PlayUCI(uci); err != nil {
+			return nil, err
 		}
 	}
+	return g, nil
+}
+
+// finishGame records the final result and broadcasts a server-built
+// game_over. Only the first call for a room has any effect.
+func (r *GameRoom) finishGame(result, reason, winner string) {
+	r.endGame("finished", result, reason, winner)
+}
+
+// endGame moves an active game to status, which is "finished" for a scored
+// game or "aborted" for one that doesn't count.
+func (r *GameRoom) endGame(status, result, reason, winner string) {
+	if r.Status != "active" {
+		return
+	}
+	r.Status = status
+	r.Clock.Stop()
+	white, black, _ := r.Clock.Snapshot()
+
+	for color, timer := range r.graceTimers {
+		timer.Stop()
+		delete(r.graceTimers, color)
+	}
+
+	now := time.Now()
+	update := database.GetDB().Model(&models.Game{}).Where("id = ? AND status = ?", r.GameID, "active").Updates(map[string]interface{}{
+		"status":               status,
+		"result":               result,
+		"reason":               reason,
+		"white_time_remaining": int(white.Seconds()),
+		"black_time_remaining": int(black.Seconds()),
+		"finished_at":          now,
+	})
+	log.Printf("Game %s ended: Result=%s, Reason=%s, Winner=%s", r.GameID, result, reason, winner)
+
+	// Only the update that actually finished the game applies ratings.
+	if status == "finished" && update.Error == nil && update.RowsAffected == 1 {
+		if err := rating.NewService().ApplyGameResult(r.GameID); err != nil {
+			log.Printf("Failed to update ratings for game %s: %v", r.GameID, err)
+		}
+		if r.onFinish != nil {
+			r.onFinish()
+		}
+	}
+	if r.AIGameID != "" {
+		r.finishAIGame(status, result, now)
+	}
+
+	r.fanOutMessage(MsgGameOver, GameOverPayload{
+		Result: result,
+		Reason: reason,
+		Winner: winner,
+	})
+
+	// Players who already left the room still hear how it ended.
+	if r.events != nil {
+		for _, userID := range []uint{r.WhiteID, r.BlackID} {
+			r.events.Publish(userID, events.GameFinished, events.GameFinishedPayload{
+				GameID: r.GameID,
+				Result: result,
+				Reason: reason,
+				Winner: winner,
+			})
+		}
+	}
+}
+
+// finishAIGame records the result of a game against the engine from the
+// human player's point of view.
+func (r *GameRoom) finishAIGame(status, result string, now time.Time) {
+	outcome := "draw"
+	switch {
+	case status == "aborted":
+		outcome = "aborted"
+	case result == chess.WinResult(roleColor(r.BotRole)):
+		outcome = "loss"
+	case result == chess.WinResult(roleColor(r.BotRole).Other()):
+		outcome = "win"
+	}
+	database.GetDB().Model(&models.AIGame{}).Where("id = ?", r.AIGameID).Updates(map[string]interface{}{
+		"status":      "finished",
+		"result":      outcome,
+		"fen":         r.Game.Position().FEN(),
+		"finished_at": now,
+	})
+}
+
+// resign ends the game as a loss for the seated client c.
+func (r *GameRoom) resign(c *Client) {
+	winner := roleColor(c.Role).Other()
+	r.finishGame(chess.WinResult(winner), "resign", winner.String())
+}
+
+// offerDraw records a draw offer from c, or agrees to a draw if the
+// opponent had already offered one.
+func (r *GameRoom) offerDraw(c *Client) {
+	if r.drawOffer == roleColor(c.Role).Other().String() {
+		r.acceptDraw(c)
+		return
+	}
+	if r.drawOffer == c.Role {
+		return
+	}
+	r.drawOffer = c.Role
+	r.fanOutMessage(MsgDrawOffer, OfferPayload{By: c.Role})
+}
+
+// acceptDraw ends the game drawn if the opponent of c has a draw offer
+// pending.
+func (r *GameRoom) acceptDraw(c *Client) {
+	if r.drawOffer == "" || r.drawOffer == c.Role {
+		c.SendError("There is no draw offer to accept")
+		return
+	}
+	r.drawOffer = ""
+	r.finishGame(chess.ResultDraw, "agreement", "")
+}
+
+// declineDraw withdraws or declines the pending draw offer.
+func (r *GameRoom) declineDraw(c *Client) {
+	if r.drawOffer == "" {
+		return
+	}
+	r.drawOffer = ""
+	r.fanOutMessage(MsgDrawDecline, OfferPayload{By: c.Role})
+}
+
+// takebackPlies returns how many half-moves a takeback for color takes
+// back: just its last move if the opponent hasn't replied yet, otherwise
+// the reply as well. It returns 0 if color has no move to take back.
+func (r *GameRoom) takebackPlies(color string) int {
+	plies := 1
+	if r.CurrentTurn == color {
+		plies = 2
+	}
+	// A custom starting position may have black moving first.
+	if plies > len(r.MoveHistory) {
+		return 0
+	}
+	return plies
+}
+
+// requestTakeback records a takeback request from c.
+func (r *GameRoom) requestTakeback(c *Client) {
+	if r.takebackPlies(c.Role) == 0 {
+		c.SendError("You have no move to take back")
+		return
+	}
+	if r.takebackRequest == c.Role {
+		return
+	}
+	r.takebackRequest = c.Role
+	r.fanOutMessage(MsgTakebackRequest, OfferPayload{By: c.Role})
+}
+
+// acceptTakeback rolls the game back to before the requester's last move:
+// the in-memory game, the stored moves and both clocks.
+func (r *GameRoom) acceptTakeback(c *Client) {
+	requester := r.takebackRequest
+	if requester == "" || requester == c.Role {
+		c.SendError("There is no takeback request to accept")
+		return
+	}
+	plies := r.takebackPlies(requester)
+	r.takebackRequest = ""
+	if plies == 0 {
+		return
+	}
+
+	keep := len(r.MoveHistory) - plies
+	for i := 0; i < plies; i++ {
+		r.Game.Undo()
+	}
+	r.MoveHistory = r.MoveHistory[:keep]
+	r.CurrentTurn = r.Game.Position().Turn().String()
+	r.drawOffer = ""
+
+	// Each side gets back the time it had when the restored turn began.
+	white, black := r.Clock.Remaining(chess.White), r.Clock.Remaining(chess.Black)
+	if keep < len(r.clockLog) {
+		white, black = r.clockLog[keep][0], r.clockLog[keep][1]
+		r.clockLog = r.clockLog[:keep]
+	}
+	r.Clock.Rewind(white, black, r.Game.Position().Turn(), keep > 0)
+	_, _, turnStart := r.Clock.Snapshot()
+
+	fen := r.Game.Position().FEN()
+	db := database.GetDB()
+	if err := db.Where("game_id = ? AND move_number > ?", r.GameID, keep).Delete(&models.Move{}).Error; err != nil {
+		log.Printf("Failed to delete taken back moves for game %s: %v", r.GameID, err)
+	}
+	updates := map[string]interface{}{
+		"white_time_remaining": int(white.Seconds()),
+		"black_time_remaining": int(black.Seconds()),
+		"fen":                  fen,
+		"last_move_at":         turnStart,
+	}
+	if keep == 0 {
+		updates["last_move_at"] = nil
+	}
+	db.Model(&models.Game{}).Where("id = ?", r.GameID).Updates(updates)
+	log.Printf("Game %s: took back %d plies for %s", r.GameID, plies, requester)
+
+	r.fanOutMessage(MsgTakebackAccept, TakebackPayload{
+		Plies:       plies,
+		FEN:         fen,
+		History:     append([]string{}, r.MoveHistory...),
+		SANHistory:  r.Game.SANHistory(),
+		WhiteTimeMs: white.Milliseconds(),
+		BlackTimeMs: black.Milliseconds(),
+		LastMoveAt:  turnStart.UnixMilli(),
+		CurrentTurn: r.CurrentTurn,
+	})
+	r.scheduleEval()
+}
+
+// playMove applies an already validated move for c: it stops the mover's
+// clock, persists the move, broadcasts it and then checks for the end.
+func (r *GameRoom) playMove(c *Client, move chess.Move) {
+	now := time.Now()
+	mover := roleColor(c.Role)
+	white, black, _ := r.Clock.Snapshot()
+	if flagged := r.Clock.Punch(now); flagged {
+		r.handleFlag(mover)
+		return
+	}
+	r.clockLog = append(r.clockLog, [2]time.Duration{white, black})
+
+	// A draw offer stands until the offerer moves; a takeback request is
+	// for the position it was made in.
+	if r.drawOffer == c.Role {
+		r.drawOffer = ""
+	}
+	r.takebackRequest = ""
+
+	white, black, _ = r.Clock.Snapshot()
+	moverLeft := white
+	if mover == chess.Black {
+		moverLeft = black
+	}
+
+	// Apply the move, noting its SAN before and the FEN after
+	san := r.Game.Position().SAN(move)
+	r.Game.Play(move)
+	fen := r.Game.Position().FEN()
+	moveStr := move.String()
+	r.MoveHistory = append(r.MoveHistory, moveStr)
+
+	// Update times and current position in database
+	database.GetDB().Model(&models.Game{}).Where("id = ?", r.GameID).Updates(map[string]interface{}{
+		"white_time_remaining": int(white.Seconds()),
+		"black_time_remaining": int(black.Seconds()),
+		"last_move_at":         now,
+		"fen":                  fen,
+	})
+
+	// Toggle Turn
+	if r.CurrentTurn == "white" {
+		r.CurrentTurn = "black"
+	} else {
+		r.CurrentTurn = "white"
+	}
+
+	// Persist move to database
+	promo := ""
+	if len(moveStr) > 4 {
+		promo = moveStr[4:]
+	}
+
+	moveRow := models.Move{
+		GameID:     r.GameID,
+		PlayerID:   c.UserID,
+		MoveNumber: len(r.MoveHistory),
+		FromSquare: move.From.String(),
+		ToSquare:   move.To.String(),
+		Promotion:  promo,
+		SAN:        san,
+		FEN:        fen,
+		ClockMs:    moverLeft.Milliseconds(),
+	}
+	if err := database.GetDB().Create(&moveRow).Error; err != nil {
+		log.Printf("Failed to save move: %v", err)
+	} else {
+		log.Printf("Saved move %d: %s (White: %v, Black: %v)", moveRow.MoveNumber, san, white, black)
+	}
+
+	// Broadcast move with current times to everyone
+	r.fanOutMessage(MsgMove, r.MovePayload(moveStr, san))
+	log.Printf("Broadcasted move from %s to room %s", c.Role, r.GameID)
+
+	r.checkGameOver()
+	r.scheduleEval()
+}
+
+// checkGameOver ends the game if the last move produced a terminal position.
+func (r *GameRoom) checkGameOver() {
+	if outcome, over := r.Game.Outcome(); over {
+		r.finishGame(outcome.Result, outcome.Reason, outcome.Winner())
+	}
+}
+
+// onFlag is the clock's callback, which fires on a timer goroutine.
+func (r *GameRoom) onFlag(color chess.Color) {
+	r.post(func() { r.handleFlag(color) })
+}
+
+// handleFlag ends the game when color's clock runs out. Running out against
+// an opponent who cannot mate is only a draw.
+func (r *GameRoom) handleFlag(color chess.Color) {
+	if !r.Game.Position().HasMatingMaterial(color.Other()) {
+		r.finishGame(chess.ResultDraw, "timeout_vs_insufficient_material", "")
+		return
+	}
+	r.finishGame(chess.WinResult(color.Other()), "timeout", color.Other().String())
+}
+
+// restoreClock sets the clock up from the stored game when it is loaded.
+func (r *GameRoom) restoreClock(g *models.Game, moves []models.Move) {
+	control, err := ParseTimeControl(g.TimeControl)
+	if err != nil {
+		control = DefaultTimeControl
+	}
+	r.Clock = NewClock(control, r.onFlag)
+
+	white, black := control.Base, control.Base
+	if len(moves) > 0 {
+		white = time.Duration(g.WhiteTimeRemaining) * time.Second
+		black = time.Duration(g.BlackTimeRemaining) * time.Second
+	}
+	// The clock log starts from the base time and follows each move's
+	// recorded clock.
+	logged := [2]time.Duration{control.Base, control.Base}
+	r.clockLog = r.clockLog[:0]
+	for i, m := range moves {
+		r.clockLog = append(r.clockLog, logged)
+		if m.ClockMs <= 0 {
+			continue
+		}
+		logged[i%2] = time.Duration(m.ClockMs) * time.Millisecond
+		if i%2 == 0 {
+			white = logged[0]
+		} else {
+			black = logged[1]
+		}
+	}
+	r.Clock.Set(white, black)
+
+	// The clock only runs once the first move has been played.
+	if len(moves) > 0 && g.Status == "active" {
+		since := time.Now()
+		if g.LastMoveAt != nil {
+			since = *g.LastMoveAt
+		}
+		r.Clock.Start(r.Game.Position().Turn(), since)
+	}
+}
+
+// MovePayload builds the broadcast for the move just played with the
+// position and clock as they stand.
+func (r *GameRoom) MovePayload(move, san string) MovePayload {
+	white, black, turnStart := r.Clock.Snapshot()
+	return MovePayload{
+		Move:        move,
+		SAN:         san,
+		FEN:         r.Game.Position().FEN(),
+		WhiteTime:   int(white.Seconds()),
+		BlackTime:   int(black.Seconds()),
+		WhiteTimeMs: white.Milliseconds(),
+		BlackTimeMs: black.Milliseconds(),
+		LastMoveAt:  turnStart.UnixMilli(),
+		CurrentTurn: r.CurrentTurn,
+	}
+}
+
+func roleColor(role string) chess.Color {
+	if role == "black" {
+		return chess.Black
+	}
+	return chess.White
 }
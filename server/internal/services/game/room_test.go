@@ -0,0 +1,50 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestStuckSpectatorDoesNotHoldUpPlayers(t *testing.T) {
+	r := NewGameRoom("stuck")
+	go r.Run()
+
+	white := newTestClient("white", 1, 16)
+	black := newTestClient("black", 2, 16)
+	// The init message fills the spectator's buffer, and they never read.
+	stuck := newTestClient("spectator", 0, 1)
+	for _, c := range []*Client{white, black, stuck} {
+		if !r.Join(c) {
+			t.Fatal("room closed")
+		}
+	}
+	receive(t, white)
+	receive(t, black)
+
+	r.Broadcast <- []byte(`{"type":"chat","payload":{"text":"hi"}}`)
+	for _, c := range []*Client{white, black} {
+		if got := string(receive(t, c)); got != `{"type":"chat","payload":{"text":"hi"}}` {
+			t.Errorf("%s got %s", c.Role, got)
+		}
+	}
+
+	r.exec(func() {
+		if r.Clients[stuck] {
+			t.Error("stuck spectator still in the room")
+		}
+		if stuck.closeCode != websocket.CloseTryAgainLater {
+			t.Errorf("close code = %d, want %d", stuck.closeCode, websocket.CloseTryAgainLater)
+		}
+	})
+	<-stuck.Send // The init message
+	if _, open := <-stuck.Send; open {
+		t.Error("stuck spectator's Send not closed")
+	}
+
+	// The dropped spectator's ReadPump may still deliver a message; the
+	// answer must not go to its closed channel.
+	for _, msg := range []WSMessage{{Type: MsgResign}, {Type: MsgMove, Payload: "e2e4"}, {Type: MsgEval}} {
+		r.exec(func() { stuck.handleMessage(r, msg) })
+	}
+}
@@ -0,0 +1,300 @@
+package game
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/chess"
+	"github.com/datmedevil17/chesss/internal/database"
+	"github.com/datmedevil17/chesss/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxImportGames caps how many games one PGN import may create.
+const MaxImportGames = 100
+
+// Service covers stored games, as opposed to the live rooms run by the Hub.
+type Service struct{}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+// GetGame loads a game with its players and moves in order.
+func (s *Service) GetGame(gameID string) (*models.Game, error) {
+	var g models.Game
+	err := database.GetDB().
+		Preload("White").
+		Preload("Black").
+		Preload("Moves", func(db *gorm.DB) *gorm.DB {
+			return db.Order("move_number ASC")
+		}).
+		Where("id = ?", gameID).
+		First(&g).Error
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// ExportPGN renders a game, finished or not, as PGN.
+func (s *Service) ExportPGN(gameID string) (string, error) {
+	g, err := s.GetGame(gameID)
+	if err != nil {
+		return "", err
+	}
+
+	result := g.Result
+	if (g.Status != "finished" && g.Status != "imported") || result == "" {
+		result = chess.ResultOngoing
+	}
+
+	date := g.CreatedAt
+	if g.StartedAt != nil {
+		date = *g.StartedAt
+	}
+
+	event := "Casual " + g.Mode + " game"
+	if g.Rated {
+		event = "Rated " + g.Mode + " game"
+	}
+
+	whiteName, blackName := g.White.Username, g.Black.Username
+	if g.Status == "imported" {
+		event = "Imported game"
+		whiteName, blackName = g.WhiteName, g.BlackName
+	}
+
+	pgn := &chess.PGNGame{
+		Tags: []chess.PGNTag{
+			{Name: "Event", Value: event},
+			{Name: "Site", Value: "Master Chess"},
+			{Name: "Date", Value: date.Format("2006.01.02")},
+			{Name: "Round", Value: "-"},
+			{Name: "White", Value: whiteName},
+			{Name: "Black", Value: blackName},
+			{Name: "Result", Value: result},
+		},
+		Result: result,
+	}
+
+	if g.Status != "imported" {
+		whiteElo, blackElo := s.eloAtStart(g)
+		if whiteElo > 0 {
+			pgn.Tags = append(pgn.Tags, chess.PGNTag{Name: "WhiteElo", Value: fmt.Sprint(whiteElo)})
+		}
+		if blackElo > 0 {
+			pgn.Tags = append(pgn.Tags, chess.PGNTag{Name: "BlackElo", Value: fmt.Sprint(blackElo)})
+		}
+	}
+	if tc, err := ParseTimeControl(g.TimeControl); err == nil {
+		pgn.Tags = append(pgn.Tags, chess.PGNTag{Name: "TimeControl", Value: exportTimeControl(tc)})
+	}
+	pgn.Tags = append(pgn.Tags, chess.PGNTag{Name: "Termination", Value: pgnTermination(g)})
+	if g.InitialFEN != "" {
+		pgn.Tags = append(pgn.Tags,
+			chess.PGNTag{Name: "SetUp", Value: "1"},
+			chess.PGNTag{Name: "FEN", Value: g.InitialFEN},
+		)
+	}
+
+	// SAN is recomputed from the UCI moves so older rows without it export too.
+	startFEN := g.InitialFEN
+	if startFEN == "" {
+		startFEN = chess.StartFEN
+	}
+	pos, err := chess.ParseFEN(startFEN)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range g.Moves {
+		move, err := pos.ParseMove(m.FromSquare + m.ToSquare + m.Promotion)
+		if err != nil {
+			return "", fmt.Errorf("move %d: %w", m.MoveNumber, err)
+		}
+		pgn.Moves = append(pgn.Moves, pos.SAN(move))
+		comment := ""
+		if m.ClockMs > 0 {
+			comment = "[%clk " + formatClock(time.Duration(m.ClockMs)*time.Millisecond) + "]"
+		}
+		pgn.Comments = append(pgn.Comments, comment)
+		pos = pos.Play(move)
+	}
+
+	return pgn.String(), nil
+}
+
+// ImportPGN validates every game in a PGN file and stores them as imported
+// games owned by userID, all or nothing. It returns the created games in
+// file order.
+func (s *Service) ImportPGN(userID uint, text string) ([]*models.Game, error) {
+	parsed, err := chess.ParsePGN(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed) > MaxImportGames {
+		return nil, fmt.Errorf("too many games: %d (max %d)", len(parsed), MaxImportGames)
+	}
+
+	games := make([]*models.Game, 0, len(parsed))
+	for i, pg := range parsed {
+		g, err := buildImportedGame(userID, pg)
+		if err != nil {
+			return nil, fmt.Errorf("game %d: %w", i+1, err)
+		}
+		games = append(games, g)
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		for _, g := range games {
+			if err := tx.Omit("White", "Black").Create(g).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+var clkComment = regexp.MustCompile(`\[%clk\s+(\d+):(\d+):(\d+(?:\.\d+)?)\]`)
+
+func buildImportedGame(userID uint, pg *chess.PGNGame) (*models.Game, error) {
+	pos, err := pg.StartPosition()
+	if err != nil {
+		return nil, err
+	}
+	board := chess.NewGame(pos)
+
+	g := &models.Game{
+		ID:          uuid.NewString(),
+		WhiteID:     userID,
+		BlackID:     userID,
+		WhiteName:   pg.Tag("White"),
+		BlackName:   pg.Tag("Black"),
+		Status:      "imported",
+		Result:      pg.Result,
+		TimeControl: importTimeControl(pg.Tag("TimeControl")),
+	}
+	if pg.Tag("FEN") != "" {
+		g.InitialFEN = pos.FEN()
+	}
+	if date, err := time.Parse("2006.01.02", pg.Tag("Date")); err == nil {
+		g.StartedAt = &date
+	}
+	switch g.Result {
+	case chess.ResultWhiteWins, chess.ResultBlackWins, chess.ResultDraw:
+	default:
+		g.Result = chess.ResultOngoing
+	}
+
+	for i, san := range pg.Moves {
+		move, err := board.Position().ParseSAN(san)
+		if err != nil {
+			return nil, fmt.Errorf("move %d (%s): %w", i+1, san, err)
+		}
+		canonical := board.Position().SAN(move)
+		board.Play(move)
+
+		uci := move.String()
+		row := models.Move{
+			PlayerID:   userID,
+			MoveNumber: i + 1,
+			FromSquare: uci[0:2],
+			ToSquare:   uci[2:4],
+			Promotion:  uci[4:],
+			SAN:        canonical,
+			FEN:        board.Position().FEN(),
+		}
+		if m := clkComment.FindStringSubmatch(pg.Comments[i]); m != nil {
+			h, _ := strconv.Atoi(m[1])
+			mins, _ := strconv.Atoi(m[2])
+			secs, _ := strconv.ParseFloat(m[3], 64)
+			row.ClockMs = int64((float64(h*3600+mins*60) + secs) * 1000)
+		}
+		g.Moves = append(g.Moves, row)
+	}
+	g.FEN = board.Position().FEN()
+
+	// The reason is only known when the final position decides it.
+	if outcome, over := board.Outcome(); over {
+		g.Reason = outcome.Reason
+	} else if pg.Tag("Termination") == "time forfeit" {
+		g.Reason = "timeout"
+	}
+	return g, nil
+}
+
+// exportTimeControl renders tc as a PGN TimeControl tag in seconds
+// ("180+2"). PGN has no notation for a Bronstein delay, so it keeps our
+// "d" suffix ("300+3d"), which importTimeControl reads back.
+func exportTimeControl(tc TimeControl) string {
+	if tc.Delay > 0 {
+		return fmt.Sprintf("%d+%dd", int(tc.Base.Seconds()), int(tc.Delay.Seconds()))
+	}
+	return fmt.Sprintf("%d+%d", int(tc.Base.Seconds()), int(tc.Increment.Seconds()))
+}
+
+// importTimeControl converts a PGN TimeControl ("180+2", or "300+3d" with a
+// delay) to our "3+2" form. Tags we can't play are dropped.
+func importTimeControl(tag string) string {
+	base, extra, _ := strings.Cut(tag, "+")
+	secs, err := strconv.Atoi(base)
+	if err != nil || secs <= 0 {
+		return ""
+	}
+	if extra == "" {
+		extra = "0"
+	}
+	tc := strconv.FormatFloat(float64(secs)/60, 'f', -1, 64) + "+" + extra
+	if _, err := ParseTimeControl(tc); err != nil {
+		return ""
+	}
+	return tc
+}
+
+// eloAtStart returns each player's rating going into the game: the
+// history row written when it finished, or else the current rating.
+func (s *Service) eloAtStart(g *models.Game) (white, black int) {
+	lookup := func(userID uint) int {
+		var h models.RatingHistory
+		if err := database.GetDB().Where("game_id = ? AND user_id = ?", g.ID, userID).First(&h).Error; err == nil {
+			return h.RatingBefore
+		}
+		var r models.Rating
+		if err := database.GetDB().Where("user_id = ? AND mode = ?", userID, g.Mode).First(&r).Error; err == nil {
+			return r.Value
+		}
+		return 0
+	}
+	return lookup(g.WhiteID), lookup(g.BlackID)
+}
+
+// pgnTermination maps Game.Reason onto the PGN Termination tag values.
+func pgnTermination(g *models.Game) string {
+	if g.Status == "aborted" {
+		return "abandoned"
+	}
+	if g.Status != "finished" && g.Status != "imported" {
+		return "unterminated"
+	}
+	switch g.Reason {
+	case "timeout", "timeout_vs_insufficient_material":
+		return "time forfeit"
+	case "abandonment":
+		return "abandoned"
+	}
+	return "normal"
+}
+
+// formatClock renders a duration as h:mm:ss for [%clk] comments.
+func formatClock(d time.Duration) string {
+	secs := int(d.Round(time.Second).Seconds())
+	return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+}
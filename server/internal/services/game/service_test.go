@@ -0,0 +1,61 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/datmedevil17/chesss/internal/chess"
+)
+
+func TestTimeControlPGNRoundTrip(t *testing.T) {
+	for _, tc := range []string{"3+2", "0.5+0", "10+0", "5+3d", "15+10"} {
+		parsed, err := ParseTimeControl(tc)
+		if err != nil {
+			t.Fatalf("ParseTimeControl(%q): %v", tc, err)
+		}
+		tag := exportTimeControl(parsed)
+		back, err := ParseTimeControl(importTimeControl(tag))
+		if err != nil || back != parsed {
+			t.Errorf("%q exported as %q imported as %+v, %v", tc, tag, back, err)
+		}
+	}
+
+	tests := map[string]string{
+		"180+2":   "3+2",
+		"300+3d":  "5+3d",
+		"600":     "10+0",
+		"":        "",
+		"?":       "",
+		"-":       "",
+		"40/9000": "",
+		"180+x":   "",
+		"0+5":     "",
+	}
+	for tag, want := range tests {
+		if got := importTimeControl(tag); got != want {
+			t.Errorf("importTimeControl(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestImportKeepsDelayAndClocks(t *testing.T) {
+	games, err := chess.ParsePGN(`[White "a"]
+[Black "b"]
+[Result "*"]
+[TimeControl "300+3d"]
+
+1. e4 { [%clk 0:05:00] } e5 { [%clk 0:04:58.5] } *
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := buildImportedGame(1, games[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.TimeControl != "5+3d" {
+		t.Errorf("time control = %q, want 5+3d", g.TimeControl)
+	}
+	if len(g.Moves) != 2 || g.Moves[0].ClockMs != 300000 || g.Moves[1].ClockMs != 298500 {
+		t.Errorf("moves = %+v", g.Moves)
+	}
+}
@@ -0,0 +1,67 @@
+package game
+
+import (
+	"log"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/database"
+	"github.com/datmedevil17/chesss/internal/models"
+)
+
+// Spectator counts are sent at most this often, so a crowd arriving at
+// once doesn't flood everyone with updates.
+const spectatorsInterval = time.Second
+
+// spectators counts the clients watching rather than playing.
+func (r *GameRoom) spectators() int {
+	n := 0
+	for c := range r.Clients {
+		if !c.Seated() && !c.IsBot {
+			n++
+		}
+	}
+	return n
+}
+
+// spectatorJoined records a signed-in spectator's visit. Anonymous
+// spectators are counted but not recorded.
+func (r *GameRoom) spectatorJoined(c *Client) {
+	if c.UserID == 0 || c.IsBot {
+		return
+	}
+	row := models.Spectator{GameID: r.GameID, UserID: c.UserID, JoinedAt: time.Now()}
+	if err := database.GetDB().Create(&row).Error; err != nil {
+		log.Printf("Game %s: failed to record spectator %d: %v", r.GameID, c.UserID, err)
+		return
+	}
+	c.spectatorID = row.ID
+}
+
+// spectatorLeft closes the spectator's visit, if it was recorded.
+func (r *GameRoom) spectatorLeft(c *Client) {
+	if c.spectatorID == 0 {
+		return
+	}
+	err := database.GetDB().Model(&models.Spectator{}).Where("id = ?", c.spectatorID).Update("left_at", time.Now()).Error
+	if err != nil {
+		log.Printf("Game %s: failed to record spectator %d leaving: %v", r.GameID, c.UserID, err)
+	}
+	c.spectatorID = 0
+}
+
+// announceSpectators tells the room how many are watching, once the last
+// update is spectatorsInterval old.
+func (r *GameRoom) announceSpectators() {
+	if r.spectatorsTimer != nil {
+		return
+	}
+	r.spectatorsTimer = time.AfterFunc(spectatorsInterval, func() {
+		r.post(func() {
+			r.spectatorsTimer = nil
+			if n := r.spectators(); n != r.spectatorsSent {
+				r.spectatorsSent = n
+				r.fanOutMessage(MsgSpectators, SpectatorsPayload{Count: n})
+			}
+		})
+	})
+}
@@ -0,0 +1,169 @@
+package game
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// How long TV stays on a finished game, so viewers see how it ended,
+	// before switching to the next one.
+	tvSwitchDelay = 5 * time.Second
+
+	// How often TV looks for a game while none is being played.
+	tvIdlePoll = 5 * time.Second
+)
+
+// Featured returns the game TV shows: the active game whose players have
+// the highest average rating.
+func (h *Hub) Featured() (LiveGame, bool) {
+	games := h.LiveGames(SortByRating)
+	if len(games) == 0 {
+		return LiveGame{}, false
+	}
+	return games[0], true
+}
+
+// ServeTV streams the featured game to conn until the viewer disconnects.
+// Each game starts with a featured message followed by that game's init;
+// after that the viewer gets the same messages as any spectator.
+func (h *Hub) ServeTV(conn *websocket.Conn, userID uint) {
+	out := &Client{Conn: conn, Send: make(chan []byte, 256), UserID: userID, Role: "spectator"}
+	done := make(chan struct{})
+	go out.WritePump()
+	go out.discardReads(done)
+	h.watchTV(out, done)
+}
+
+// watchTV follows featured games for out until done is closed, joining
+// each game's room with a spectator client of its own and relaying what
+// that client receives.
+func (h *Hub) watchTV(out *Client, done <-chan struct{}) {
+	defer close(out.Send)
+
+	waiting := false
+	for {
+		g, ok := h.Featured()
+		if !ok {
+			if !waiting && !out.sendTV(MsgFeatured, FeaturedPayload{}) {
+				return
+			}
+			waiting = true
+			if !sleep(tvIdlePoll, done) {
+				return
+			}
+			continue
+		}
+		waiting = false
+
+		viewer := &Client{Send: make(chan []byte, 256), UserID: out.UserID, Role: "spectator"}
+		room, err := h.JoinRoom(g.GameID, viewer)
+		if err != nil {
+			log.Printf("TV: failed to join game %s: %v", g.GameID, err)
+			if !sleep(tvIdlePoll, done) {
+				return
+			}
+			continue
+		}
+		if !out.sendTV(MsgFeatured, FeaturedPayload{
+			GameID:      g.GameID,
+			WhiteRating: g.WhiteRating,
+			BlackRating: g.BlackRating,
+			Mode:        g.Mode,
+			TimeControl: g.TimeControl,
+		}) {
+			room.Leave(viewer)
+			return
+		}
+
+		finished, ok := out.relay(viewer, done)
+		room.Leave(viewer)
+		if !ok {
+			return
+		}
+		if finished && !sleep(tvSwitchDelay, done) {
+			return
+		}
+	}
+}
+
+// relay copies viewer's messages to out until the game ends, the room
+// drops viewer, or done is closed. finished reports whether the game
+// ended; ok is false once out can't take any more.
+func (out *Client) relay(viewer *Client, done <-chan struct{}) (finished, ok bool) {
+	for {
+		select {
+		case msg, open := <-viewer.Send:
+			if !open {
+				return false, true
+			}
+			select {
+			case out.Send <- msg:
+			default:
+				log.Printf("TV: disconnecting slow viewer (User %d)", out.UserID)
+				out.closeCode, out.closeReason = websocket.CloseTryAgainLater, "Connection too slow"
+				return false, false
+			}
+			var m botMessage
+			if json.Unmarshal(msg, &m) != nil {
+				continue
+			}
+			switch m.Type {
+			case MsgGameOver:
+				return true, true
+			case MsgInit:
+				// The game may have ended between choosing and joining it.
+				var init InitPayload
+				if json.Unmarshal(m.Payload, &init) == nil && init.Status != "active" {
+					return true, true
+				}
+			}
+		case <-done:
+			return false, false
+		}
+	}
+}
+
+// sendTV queues a message for a TV viewer, reporting false if their
+// buffer is full.
+func (out *Client) sendTV(t MessageType, payload interface{}) bool {
+	bytes, err := json.Marshal(WSMessage{Type: t, Payload: payload})
+	if err != nil {
+		return false
+	}
+	select {
+	case out.Send <- bytes:
+		return true
+	default:
+		return false
+	}
+}
+
+// discardReads reads from the connection until it fails, keeping the
+// read deadline fresh, then closes done. TV viewers have nothing to say.
+func (c *Client) discardReads(done chan<- struct{}) {
+	defer close(done)
+	c.Conn.SetReadLimit(maxMessageSize)
+	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.Conn.SetPongHandler(func(string) error { c.Conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
+	for {
+		if _, _, err := c.Conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// sleep waits for d, reporting false if done is closed first.
+func sleep(d time.Duration, done <-chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+		return false
+	}
+}
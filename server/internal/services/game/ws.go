@@ -23,9 +23,13 @@ func ServeWs(hub *Hub, c *gin.Context, gameID string) {
 		return
 	}
 
-	room := hub.GetRoom(gameID)
-	client := &Client{Conn: conn, Send: make(chan []byte, 256)}
-	room.Register <- client
+	client := &Client{Conn: conn, Send: make(chan []byte, 256), Role: "spectator"}
+	room, err := hub.JoinRoom(gameID, client)
+	if err != nil {
+		log.Println(err)
+		conn.Close()
+		return
+	}
 
 	// Allow collection of memory referenced by the caller by doing all work in
 	// new goroutines.
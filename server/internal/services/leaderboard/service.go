@@ -0,0 +1,140 @@
+package leaderboard
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/database"
+	"github.com/datmedevil17/chesss/internal/services/rating"
+)
+
+const (
+	// Players who haven't finished a rated game in a mode for this long
+	// drop off its leaderboard until they play again.
+	activeWithin = 30 * 24 * time.Hour
+
+	// How long a built leaderboard is served before it is rebuilt.
+	cacheTTL = time.Minute
+
+	DefaultLimit = 50
+	MaxLimit     = 100
+)
+
+// Modes are the rated game modes with a leaderboard.
+var Modes = []string{"bullet", "blitz", "rapid"}
+
+var ErrUnknownMode = errors.New("unknown mode")
+
+// Entry is one ranked player. Players with the same rating share a rank.
+type Entry struct {
+	Rank        int
+	UserID      uint
+	Username    string
+	Rating      int
+	Deviation   float64
+	GamesPlayed int
+	Wins        int
+	Losses      int
+	Draws       int
+}
+
+// Board is a mode's leaderboard as of BuiltAt.
+type Board struct {
+	Mode    string
+	Entries []Entry
+	BuiltAt time.Time
+	ranks   map[uint]int // user ID -> index into Entries
+}
+
+// Service serves leaderboards from memory, rebuilding each one with a
+// single indexed query at most once per cacheTTL.
+type Service struct {
+	mu     sync.Mutex
+	boards map[string]*Board
+}
+
+func NewService() *Service {
+	return &Service{boards: make(map[string]*Board)}
+}
+
+// Top returns the best limit players in mode, and the board they came from.
+func (s *Service) Top(mode string, limit int) (*Board, []Entry, error) {
+	b, err := s.board(mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	if limit < 1 || limit > MaxLimit {
+		limit = DefaultLimit
+	}
+	return b, b.Entries[:min(limit, len(b.Entries))], nil
+}
+
+// Rank returns the user's entry on the board, if they are ranked.
+func (b *Board) Rank(userID uint) (Entry, bool) {
+	i, ok := b.ranks[userID]
+	if !ok {
+		return Entry{}, false
+	}
+	return b.Entries[i], true
+}
+
+func (s *Service) board(mode string) (*Board, error) {
+	if !validMode(mode) {
+		return nil, ErrUnknownMode
+	}
+
+	// Builds happen under the lock so a burst of requests after expiry
+	// queries the database once.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b := s.boards[mode]; b != nil && time.Since(b.BuiltAt) < cacheTTL {
+		return b, nil
+	}
+	b, err := build(mode)
+	if err != nil {
+		return nil, err
+	}
+	s.boards[mode] = b
+	return b, nil
+}
+
+// build ranks every eligible player in mode: not banned, not a bot, with
+// an established rating and a rated game within activeWithin.
+func build(mode string) (*Board, error) {
+	now := time.Now()
+	var entries []Entry
+	err := database.GetDB().
+		Table("ratings").
+		Select("ratings.user_id, users.username, ratings.value AS rating, ratings.deviation, "+
+			"ratings.games_played, ratings.wins, ratings.losses, ratings.draws").
+		Joins("JOIN users ON users.id = ratings.user_id").
+		Where("ratings.mode = ? AND ratings.deviation <= ? AND ratings.updated_at >= ?",
+			mode, rating.ProvisionalDeviation, now.Add(-activeWithin)).
+		Where("users.is_banned = ? AND users.is_bot = ?", false, false).
+		Order("ratings.value DESC, ratings.deviation ASC, ratings.user_id ASC").
+		Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Board{Mode: mode, Entries: entries, BuiltAt: now, ranks: make(map[uint]int, len(entries))}
+	for i := range entries {
+		if i > 0 && entries[i].Rating == entries[i-1].Rating {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+		b.ranks[entries[i].UserID] = i
+	}
+	return b, nil
+}
+
+func validMode(mode string) bool {
+	for _, m := range Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
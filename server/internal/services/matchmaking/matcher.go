@@ -0,0 +1,125 @@
+package matchmaking
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/datmedevil17/chesss/internal/database"
+	"github.com/datmedevil17/chesss/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// How often the background matcher scans the queue.
+	MatchInterval = 2 * time.Second
+
+	// Most queue entries one scan looks at, oldest first.
+	matchBatchSize = 500
+)
+
+// RunMatcher pairs queued players every MatchInterval until ctx ends, so a
+// player is matched even if nobody joins after them.
+func (s *Service) RunMatcher(ctx context.Context) {
+	ticker := time.NewTicker(MatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.MatchQueued(); err != nil {
+				log.Printf("Matchmaking pass failed: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// MatchQueued makes every match the queue currently allows, widening the
+// rating windows of players who have waited, and returns the games it
+// created. Entries locked by a concurrent TryMatch are left to it.
+func (s *Service) MatchQueued() ([]*models.Game, error) {
+	var games []*models.Game
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var queue []models.MatchmakingQueue
+		if err := tx.Clauses(skipLocked).
+			Order("joined_at ASC").
+			Limit(matchBatchSize).
+			Find(&queue).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		widened := make([]bool, len(queue))
+		for i := range queue {
+			widened[i] = widen(&queue[i], now)
+		}
+
+		// Oldest first, each player takes the longest-waiting opponent
+		// whose window overlaps theirs.
+		matched := make([]bool, len(queue))
+		for i := range queue {
+			if matched[i] {
+				continue
+			}
+			for j := i + 1; j < len(queue); j++ {
+				if matched[j] || !compatible(queue[i], queue[j]) {
+					continue
+				}
+				game, err := createGame(tx, queue[i], queue[j])
+				if err != nil {
+					return err
+				}
+				matched[i], matched[j] = true, true
+				games = append(games, game)
+				break
+			}
+		}
+
+		for i, entry := range queue {
+			if matched[i] || !widened[i] {
+				continue
+			}
+			if err := tx.Model(&models.MatchmakingQueue{}).Where("id = ?", entry.ID).Updates(map[string]interface{}{
+				"min_rating": entry.MinRating,
+				"max_rating": entry.MaxRating,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, game := range games {
+		s.notify(game)
+	}
+	return games, nil
+}
+
+// widen grows an entry's rating window for the time it has waited. The
+// window stays centered on the player's rating and never shrinks; it reports
+// whether the window changed.
+func widen(entry *models.MatchmakingQueue, now time.Time) bool {
+	half := baseWindow + windowStep*int(now.Sub(entry.JoinedAt)/widenEvery)
+	if half > maxWindow {
+		half = maxWindow
+	}
+	center := (entry.MinRating + entry.MaxRating) / 2
+	if half <= (entry.MaxRating-entry.MinRating)/2 {
+		return false
+	}
+	entry.MinRating, entry.MaxRating = center-half, center+half
+	return true
+}
+
+// compatible reports whether two queue entries can play each other.
+func compatible(a, b models.MatchmakingQueue) bool {
+	return a.UserID != b.UserID &&
+		a.Mode == b.Mode &&
+		a.TimeControl == b.TimeControl &&
+		a.MinRating <= b.MaxRating &&
+		a.MaxRating >= b.MinRating
+}
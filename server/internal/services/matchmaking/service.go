@@ -2,37 +2,97 @@ package matchmaking
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/datmedevil17/chesss/internal/database"
 	"github.com/datmedevil17/chesss/internal/models"
+	"github.com/datmedevil17/chesss/internal/services/game"
+	"github.com/datmedevil17/chesss/internal/services/rating"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type Service struct{}
+// Rating windows: players start within baseWindow points of their rating
+// and the window grows by windowStep every widenEvery they wait, up to
+// maxWindow.
+const (
+	baseWindow = 100
+	windowStep = 50
+	widenEvery = 10 * time.Second
+	maxWindow  = 500
+)
+
+var (
+	ErrNotInQueue = errors.New("not in queue")
+	ErrNoOpponent = errors.New("no opponent yet")
+)
 
-func NewService() *Service {
-	return &Service{}
+// skipLocked makes a query pass over queue entries another transaction is
+// already matching, so no player is ever put in two games.
+var skipLocked = clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}
+
+// Notifier tells players about the games matchmaking made for them.
+type Notifier interface {
+	MatchFound(game *models.Game)
+}
+
+type Service struct {
+	ratings  *rating.Service
+	notifier Notifier
+}
+
+// NewService returns the matchmaking service; notifier may be nil, in which
+// case players only learn of matches through CheckActiveMatch.
+func NewService(notifier Notifier) *Service {
+	return &Service{
+		ratings:  rating.NewService(),
+		notifier: notifier,
+	}
 }
 
-// Join queue
+// Join queue. The rating window comes from the user's stored rating for
+// the mode, never from the client. Joining again replaces the user's
+// earlier entry.
 func (s *Service) JoinQueue(
 	userID uint,
 	mode string,
 	timeControl string,
-	rating int,
 ) error {
+	switch mode {
+	case "bullet", "blitz", "rapid":
+	default:
+		return fmt.Errorf("invalid mode %q", mode)
+	}
+	tc, err := game.ParseTimeControl(timeControl)
+	if err != nil {
+		return err
+	}
+	if tc.Mode() != mode {
+		return fmt.Errorf("time control %s is %s, not %s", timeControl, tc.Mode(), mode)
+	}
+
+	r, err := s.ratings.GetRating(userID, mode)
+	if err != nil {
+		return err
+	}
+	rating := r.Value
 
 	entry := &models.MatchmakingQueue{
 		UserID:      userID,
 		Mode:        mode,
 		TimeControl: timeControl,
-		MinRating:   rating - 100,
-		MaxRating:   rating + 100,
+		MinRating:   rating - baseWindow,
+		MaxRating:   rating + baseWindow,
 		JoinedAt:    time.Now(),
 	}
 
-	return database.GetDB().Create(entry).Error
+	return database.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mode", "time_control", "min_rating", "max_rating", "joined_at"}),
+	}).Create(entry).Error
 }
 
 // Leave queue
@@ -43,38 +103,53 @@ func (s *Service) LeaveQueue(userID uint) error {
 		Error
 }
 
-// Try to match user
+// Try to match user with the longest-waiting compatible opponent. Entries
+// the background matcher is working on are skipped.
 func (s *Service) TryMatch(userID uint) (*models.Game, error) {
-	var player models.MatchmakingQueue
-	if err := database.GetDB().
-		Where("user_id = ?", userID).
-		First(&player).Error; err != nil {
-		return nil, errors.New("not in queue")
-	}
-
-	var opponent models.MatchmakingQueue
-	err := database.GetDB().
-		Where(`
-			mode = ?
-			AND time_control = ?
-			AND user_id != ?
-			AND min_rating <= ?
-			AND max_rating >= ?
-		`,
-			player.Mode,
-			player.TimeControl,
-			player.UserID,
-			player.MaxRating,
-			player.MinRating,
-		).
-		Order("joined_at ASC").
-		First(&opponent).Error
-
+	var game *models.Game
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var player models.MatchmakingQueue
+		if err := tx.Clauses(skipLocked).
+			Where("user_id = ?", userID).
+			First(&player).Error; err != nil {
+			return ErrNotInQueue
+		}
+
+		var opponent models.MatchmakingQueue
+		err := tx.Clauses(skipLocked).
+			Where(`
+				mode = ?
+				AND time_control = ?
+				AND user_id != ?
+				AND min_rating <= ?
+				AND max_rating >= ?
+			`,
+				player.Mode,
+				player.TimeControl,
+				player.UserID,
+				player.MaxRating,
+				player.MinRating,
+			).
+			Order("joined_at ASC").
+			First(&opponent).Error
+		if err != nil {
+			return ErrNoOpponent
+		}
+
+		game, err = createGame(tx, player, opponent)
+		return err
+	})
 	if err != nil {
-		return nil, errors.New("no opponent yet")
+		return nil, err
 	}
 
-	// Create game
+	s.notify(game)
+	return game, nil
+}
+
+// createGame starts a rated game between two queue entries, which the
+// caller has locked, with colors decided by a coin toss.
+func createGame(tx *gorm.DB, player, opponent models.MatchmakingQueue) (*models.Game, error) {
 	// Randomize Color
 	var whiteID, blackID uint
 	if time.Now().UnixNano()%2 == 0 {
@@ -84,39 +159,45 @@ func (s *Service) TryMatch(userID uint) (*models.Game, error) {
 		whiteID = opponent.UserID
 		blackID = player.UserID
 	}
+	return CreateGame(tx, whiteID, blackID, player.Mode, player.TimeControl, true)
+}
 
+// CreateGame starts a game between two players and takes both out of the
+// matchmaking queue. Matches and accepted challenges both start here.
+func CreateGame(tx *gorm.DB, whiteID, blackID uint, mode, timeControl string, rated bool) (*models.Game, error) {
 	game := &models.Game{
 		ID:          uuid.NewString(),
 		WhiteID:     whiteID,
 		BlackID:     blackID,
 		Status:      "active",
-		Mode:        player.Mode,
-		TimeControl: player.TimeControl,
+		Mode:        mode,
+		TimeControl: timeControl,
+		Rated:       rated,
 		FEN:         "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
 		StartedAt:   ptrTime(time.Now()),
 	}
 
-	tx := database.GetDB().Begin()
 	if err := tx.Create(game).Error; err != nil {
-		tx.Rollback()
 		return nil, err
 	}
-
-	tx.Where("user_id IN ?", []uint{
-		player.UserID,
-		opponent.UserID,
-	}).Delete(&models.MatchmakingQueue{})
-
-	tx.Commit()
-
+	if err := tx.Where("user_id IN ?", []uint{whiteID, blackID}).Delete(&models.MatchmakingQueue{}).Error; err != nil {
+		return nil, err
+	}
 	return game, nil
 }
 
+func (s *Service) notify(game *models.Game) {
+	log.Printf("Matched users %d and %d in game %s", game.WhiteID, game.BlackID, game.ID)
+	if s.notifier != nil {
+		s.notifier.MatchFound(game)
+	}
+}
+
 // Check if user has an active match (polling)
 func (s *Service) CheckActiveMatch(userID uint) (*models.Game, error) {
 	var game models.Game
 	if err := database.GetDB().
-		Where("(white_id = ? OR black_id = ?) AND status = 'active'", userID, userID).
+		Where("(white_id = ? OR black_id = ?) AND status = 'active' AND mode <> 'ai'", userID, userID).
 		Order("started_at DESC").
 		First(&game).Error; err != nil {
 		return nil, err
@@ -0,0 +1,89 @@
+package rating
+
+import "math"
+
+// Glicko-2 as described in Mark Glickman's "Example of the Glicko-2 system".
+// Every finished game is treated as its own rating period.
+
+const (
+	glickoScale  = 173.7178
+	glickoCenter = 1500.0
+	tau          = 0.5 // constrains volatility change; 0.3-1.2 is reasonable
+	epsilon      = 0.000001
+
+	// New players start at the models.Rating defaults.
+	InitialRating     = 1200
+	InitialDeviation  = 350.0
+	InitialVolatility = 0.06
+
+	// A rating whose deviation is above this is still provisional: too
+	// uncertain to rank or show without a question mark.
+	ProvisionalDeviation = 110.0
+)
+
+type Glicko struct {
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+}
+
+// Update returns player's new rating after scoring score (1, 0.5 or 0)
+// against opponent.
+func Update(player, opponent Glicko, score float64) Glicko {
+	mu := (player.Rating - glickoCenter) / glickoScale
+	phi := player.Deviation / glickoScale
+	muJ := (opponent.Rating - glickoCenter) / glickoScale
+	phiJ := opponent.Deviation / glickoScale
+
+	g := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+	e := 1 / (1 + math.Exp(-g*(mu-muJ)))
+	v := 1 / (g * g * e * (1 - e))
+	delta := v * g * (score - e)
+
+	sigma := newVolatility(phi, player.Volatility, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phiNew := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	muNew := mu + phiNew*phiNew*g*(score-e)
+
+	return Glicko{
+		Rating:     glickoScale*muNew + glickoCenter,
+		Deviation:  math.Min(glickoScale*phiNew, InitialDeviation),
+		Volatility: sigma,
+	}
+}
+
+// newVolatility solves for sigma' with the Illinois algorithm (step 5).
+func newVolatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
@@ -0,0 +1,156 @@
+package rating
+
+import (
+	"errors"
+	"log"
+	"math"
+
+	"github.com/datmedevil17/chesss/internal/database"
+	"github.com/datmedevil17/chesss/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Service struct{}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+// GetRating returns the user's rating for mode, creating it at the initial
+// values the first time the user plays that mode.
+func (s *Service) GetRating(userID uint, mode string) (*models.Rating, error) {
+	return getOrCreate(database.GetDB(), userID, mode, false)
+}
+
+// ApplyGameResult updates both players' ratings for a finished rated game
+// and writes a history row for each. Applying the same game twice is a
+// no-op, so callers don't need to coordinate.
+func (s *Service) ApplyGameResult(gameID string) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := tx.Where("id = ?", gameID).First(&game).Error; err != nil {
+			return err
+		}
+		if !game.Rated || game.Status != "finished" || game.Mode == "" || game.Mode == "ai" {
+			return nil
+		}
+
+		var whiteScore float64
+		switch game.Result {
+		case "1-0":
+			whiteScore = 1
+		case "0-1":
+			whiteScore = 0
+		case "1/2-1/2":
+			whiteScore = 0.5
+		default:
+			return nil
+		}
+
+		var applied int64
+		tx.Model(&models.RatingHistory{}).Where("game_id = ?", gameID).Count(&applied)
+		if applied > 0 {
+			return nil
+		}
+
+		white, err := getOrCreate(tx, game.WhiteID, game.Mode, true)
+		if err != nil {
+			return err
+		}
+		black, err := getOrCreate(tx, game.BlackID, game.Mode, true)
+		if err != nil {
+			return err
+		}
+
+		// Both updates use the pre-game ratings.
+		whiteBefore, blackBefore := toGlicko(white), toGlicko(black)
+		if err := applyUpdate(tx, white, Update(whiteBefore, blackBefore, whiteScore), whiteScore, gameID); err != nil {
+			return err
+		}
+		if err := applyUpdate(tx, black, Update(blackBefore, whiteBefore, 1-whiteScore), 1-whiteScore, gameID); err != nil {
+			return err
+		}
+
+		log.Printf("Ratings updated for game %s: white %d -> %d, black %d -> %d",
+			gameID, int(math.Round(whiteBefore.Rating)), white.Value, int(math.Round(blackBefore.Rating)), black.Value)
+		return nil
+	})
+}
+
+func getOrCreate(tx *gorm.DB, userID uint, mode string, lock bool) (*models.Rating, error) {
+	q := tx
+	if lock {
+		q = q.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var r models.Rating
+	err := q.Where("user_id = ? AND mode = ?", userID, mode).First(&r).Error
+	if err == nil {
+		return &r, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	r = models.Rating{
+		UserID:     userID,
+		Mode:       mode,
+		Value:      InitialRating,
+		Deviation:  InitialDeviation,
+		Volatility: InitialVolatility,
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&r).Error; err != nil {
+		return nil, err
+	}
+	// Another request may have created the row first; read back whichever won.
+	if err := q.Where("user_id = ? AND mode = ?", userID, mode).First(&r).Error; err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func toGlicko(r *models.Rating) Glicko {
+	g := Glicko{
+		Rating:     float64(r.Value),
+		Deviation:  r.Deviation,
+		Volatility: r.Volatility,
+	}
+	if g.Deviation <= 0 {
+		g.Deviation = InitialDeviation
+	}
+	if g.Volatility <= 0 {
+		g.Volatility = InitialVolatility
+	}
+	return g
+}
+
+func applyUpdate(tx *gorm.DB, r *models.Rating, next Glicko, score float64, gameID string) error {
+	history := models.RatingHistory{
+		UserID:       r.UserID,
+		GameID:       gameID,
+		Mode:         r.Mode,
+		RatingBefore: r.Value,
+		RatingAfter:  int(math.Round(next.Rating)),
+		Deviation:    next.Deviation,
+		Volatility:   next.Volatility,
+	}
+
+	r.Value = history.RatingAfter
+	r.Deviation = next.Deviation
+	r.Volatility = next.Volatility
+	r.GamesPlayed++
+	switch score {
+	case 1:
+		r.Wins++
+	case 0:
+		r.Losses++
+	default:
+		r.Draws++
+	}
+
+	if err := tx.Omit(clause.Associations).Save(r).Error; err != nil {
+		return err
+	}
+	return tx.Create(&history).Error
+}
@@ -0,0 +1,117 @@
+package user
+
+import (
+	"github.com/datmedevil17/chesss/internal/database"
+	"github.com/datmedevil17/chesss/internal/models"
+)
+
+// How many recent games a profile lists.
+const recentGamesLimit = 10
+
+// Record counts results from one player's side.
+type Record struct {
+	Games  int
+	Wins   int
+	Losses int
+	Draws  int
+}
+
+func (r *Record) add(score float64) {
+	r.Games++
+	switch score {
+	case 1:
+		r.Wins++
+	case 0:
+		r.Losses++
+	default:
+		r.Draws++
+	}
+}
+
+// Streaks are runs of consecutive results, in the order games finished.
+type Streaks struct {
+	LongestWin  int
+	LongestLoss int
+	Current     int // Positive for a winning run, negative for a losing one
+}
+
+// Profile is what anyone can see about a user. It never includes their
+// email address.
+type Profile struct {
+	User        *models.User
+	Ratings     []models.Rating
+	Overall     Record
+	AsWhite     Record
+	AsBlack     Record
+	Streaks     Streaks
+	RecentGames []models.Game
+}
+
+// GetProfile gathers a user's public statistics. Only finished games they
+// played count; imported and aborted games don't.
+func (s *Service) GetProfile(username string) (*Profile, error) {
+	u, err := s.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	p := &Profile{User: u}
+
+	if err := database.GetDB().Where("user_id = ?", u.ID).Order("mode ASC").Find(&p.Ratings).Error; err != nil {
+		return nil, err
+	}
+
+	var results []struct {
+		WhiteID uint
+		Result  string
+	}
+	err = database.GetDB().Model(&models.Game{}).
+		Select("white_id, result").
+		Where("(white_id = ? OR black_id = ?) AND status = ?", u.ID, u.ID, "finished").
+		Order("finished_at ASC, created_at ASC").
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	win, loss := 0, 0
+	for _, g := range results {
+		asWhite := g.WhiteID == u.ID
+		score := 0.5
+		switch {
+		case g.Result == "1-0" && asWhite, g.Result == "0-1" && !asWhite:
+			score = 1
+		case g.Result == "0-1" && asWhite, g.Result == "1-0" && !asWhite:
+			score = 0
+		}
+
+		p.Overall.add(score)
+		if asWhite {
+			p.AsWhite.add(score)
+		} else {
+			p.AsBlack.add(score)
+		}
+
+		switch score {
+		case 1:
+			win, loss = win+1, 0
+		case 0:
+			win, loss = 0, loss+1
+		default:
+			win, loss = 0, 0
+		}
+		p.Streaks.LongestWin = max(p.Streaks.LongestWin, win)
+		p.Streaks.LongestLoss = max(p.Streaks.LongestLoss, loss)
+	}
+	p.Streaks.Current = win - loss
+
+	err = database.GetDB().
+		Preload("White").
+		Preload("Black").
+		Where("(white_id = ? OR black_id = ?) AND status IN ?", u.ID, u.ID, []string{"active", "finished"}).
+		Order("created_at DESC").
+		Limit(recentGamesLimit).
+		Find(&p.RecentGames).Error
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
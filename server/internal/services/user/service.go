@@ -28,6 +28,15 @@ func (s *Service) GetByEmail(email string) (*models.User, error) {
 	return &user, err
 }
 
+func (s *Service) GetByUsername(username string) (*models.User, error) {
+	var user models.User
+	err := database.GetDB().
+		Where("username = ?", username).
+		First(&user).
+		Error
+	return &user, err
+}
+
 func (s *Service) Create(user *models.User) error {
 	return database.GetDB().Create(user).Error
 }